@@ -0,0 +1,83 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package azure holds the Microsoft Entra ID credentials shared by the Azure plugins
+package azure
+
+import (
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+type CredentialType string
+
+const (
+	ClientSecretCredential     CredentialType = "client_secret"
+	ManagedIdentityCredential  CredentialType = "managed_identity"
+	WorkloadIdentityCredential CredentialType = "workload_identity"
+	DefaultCredential          CredentialType = "default"
+)
+
+type Credential struct {
+	Type         CredentialType `json:"type"`
+	TenantID     string         `json:"tenant_id"`
+	ClientID     string         `json:"client_id"`
+	ClientSecret string         `json:"client_secret"`
+}
+
+// Validate checks the Entra ID credential, plugins handle their own key based types first
+func (c Credential) Validate() error {
+	switch c.Type {
+	case ClientSecretCredential:
+		if c.TenantID == "" || c.ClientID == "" || c.ClientSecret == "" {
+			return fmt.Errorf("TenantID, ClientID and ClientSecret are mandatory with client secret credentials")
+		}
+	case ManagedIdentityCredential, WorkloadIdentityCredential, DefaultCredential:
+	default:
+		return fmt.Errorf("Unknown credential type: %s", c.Type)
+	}
+	return nil
+}
+
+// NewTokenCredential returns the Entra ID token credential for the type,
+// falling back to the default Azure credential chain
+func NewTokenCredential(c Credential) (azcore.TokenCredential, error) {
+	switch c.Type {
+	case ClientSecretCredential:
+		return azidentity.NewClientSecretCredential(c.TenantID, c.ClientID, c.ClientSecret, nil)
+	case ManagedIdentityCredential:
+		options := &azidentity.ManagedIdentityCredentialOptions{}
+		// user assigned identity
+		if c.ClientID != "" {
+			options.ID = azidentity.ClientID(c.ClientID)
+		}
+		return azidentity.NewManagedIdentityCredential(options)
+	case WorkloadIdentityCredential:
+		// unset values are read from the AZURE_* environment variables
+		return azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			TenantID: c.TenantID,
+			ClientID: c.ClientID,
+		})
+	default:
+		return azidentity.NewDefaultAzureCredential(nil)
+	}
+}
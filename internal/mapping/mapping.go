@@ -0,0 +1,56 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package mapping maps jr record headers onto the metadata, tags or headers
+// of the target system
+package mapping
+
+import "slices"
+
+// Mapping defines static values, optionally extended with the jr headers
+// restricted to the Headers names when not empty
+type Mapping struct {
+	Static      map[string]string `json:"static"`
+	FromHeaders bool              `json:"from_headers"`
+	Headers     []string          `json:"headers"`
+}
+
+// Values returns the static values merged with the selected jr headers,
+// nil when there are none
+func (m Mapping) Values(headers map[string]string) map[string]string {
+	values := make(map[string]string, len(m.Static))
+	for k, v := range m.Static {
+		values[k] = v
+	}
+
+	if m.FromHeaders {
+		for k, v := range headers {
+			if len(m.Headers) > 0 && !slices.Contains(m.Headers, k) {
+				continue
+			}
+			values[k] = v
+		}
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
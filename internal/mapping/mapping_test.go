@@ -0,0 +1,69 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mapping_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/mapping"
+)
+
+func TestValues(t *testing.T) {
+	headers := map[string]string{"source": "jr", "other": "x"}
+
+	tests := []struct {
+		name    string
+		mapping mapping.Mapping
+		want    map[string]string
+	}{
+		{
+			name:    "empty",
+			mapping: mapping.Mapping{},
+			want:    nil,
+		},
+		{
+			name:    "static only",
+			mapping: mapping.Mapping{Static: map[string]string{"env": "test"}},
+			want:    map[string]string{"env": "test"},
+		},
+		{
+			name:    "all headers",
+			mapping: mapping.Mapping{Static: map[string]string{"env": "test"}, FromHeaders: true},
+			want:    map[string]string{"env": "test", "source": "jr", "other": "x"},
+		},
+		{
+			name: "allowed headers",
+			mapping: mapping.Mapping{
+				Static:      map[string]string{"env": "test"},
+				FromHeaders: true,
+				Headers:     []string{"source"},
+			},
+			want: map[string]string{"env": "test", "source": "jr"},
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, tt.mapping.Values(headers)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
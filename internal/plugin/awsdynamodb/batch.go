@@ -0,0 +1,240 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBatchSize is the maximum number of items accepted by BatchWriteItem
+	MaxBatchSize = 25
+
+	defaultMaxRetries     = 5
+	defaultInitialBackoff = 50 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+)
+
+// UnprocessedItemsError reports the items DynamoDB did not write
+// after all the retries have been exhausted
+type UnprocessedItemsError struct {
+	Unprocessed int
+	Total       int
+	Retries     int
+}
+
+func (e *UnprocessedItemsError) Error() string {
+	return fmt.Sprintf("%d of %d items unprocessed after %d retries", e.Unprocessed, e.Total, e.Retries)
+}
+
+func validateBatch(config *Batch) error {
+	var err error
+
+	if config.Size == 0 {
+		config.Size = MaxBatchSize
+	}
+	if config.Size < 0 || config.Size > MaxBatchSize {
+		return fmt.Errorf("Batch size must be between 1 and %d", MaxBatchSize)
+	}
+
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+
+	config.initialBackoff = defaultInitialBackoff
+	if config.InitialBackoff != "" {
+		config.initialBackoff, err = time.ParseDuration(config.InitialBackoff)
+		if err != nil {
+			return err
+		}
+	}
+
+	config.maxBackoff = defaultMaxBackoff
+	if config.MaxBackoff != "" {
+		config.maxBackoff, err = time.ParseDuration(config.MaxBackoff)
+		if err != nil {
+			return err
+		}
+	}
+
+	if config.initialBackoff <= 0 || config.maxBackoff <= 0 {
+		return fmt.Errorf("Batch backoffs must be positive")
+	}
+
+	return nil
+}
+
+// batchWriteClient is the subset of dynamodb.Client used by the batch writer
+type batchWriteClient interface {
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// batchWriter groups items into BatchWriteItem requests. BatchWriteItem rejects
+// a request holding two items with the same key, so an item replaces the
+// pending one with the same key, the last write wins as with PutItem
+type batchWriter struct {
+	mu sync.Mutex
+
+	client batchWriteClient
+	table  string
+	key    Key
+	config Batch
+	items  []types.WriteRequest
+	keys   map[string]int
+}
+
+func newBatchWriter(client batchWriteClient, table string, key Key, config Batch) *batchWriter {
+	return &batchWriter{
+		client: client,
+		table:  table,
+		key:    key,
+		config: config,
+		items:  make([]types.WriteRequest, 0, config.Size),
+		keys:   make(map[string]int, config.Size),
+	}
+}
+
+// Add buffers the item and writes the batch once it is full,
+// returning the number of items written
+func (b *batchWriter) Add(ctx context.Context, item map[string]types.AttributeValue) (int, error) {
+	key, err := itemKey(item, b.key)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	request := types.WriteRequest{
+		PutRequest: &types.PutRequest{Item: item},
+	}
+	if i, ok := b.keys[key]; ok {
+		b.items[i] = request
+		return 0, nil
+	}
+	b.keys[key] = len(b.items)
+	b.items = append(b.items, request)
+
+	if len(b.items) < b.config.Size {
+		return 0, nil
+	}
+	return b.flush(ctx)
+}
+
+// itemKey returns a string identifying the primary key of the item
+func itemKey(item map[string]types.AttributeValue, key Key) (string, error) {
+	var b strings.Builder
+	for _, name := range key.names() {
+		switch v := item[name].(type) {
+		case *types.AttributeValueMemberS:
+			fmt.Fprintf(&b, "S%d:%s", len(v.Value), v.Value)
+		case *types.AttributeValueMemberN:
+			fmt.Fprintf(&b, "N%d:%s", len(v.Value), v.Value)
+		case *types.AttributeValueMemberB:
+			fmt.Fprintf(&b, "B%d:%s", len(v.Value), v.Value)
+		case nil:
+			return "", fmt.Errorf("Key attribute %s not found in item", name)
+		default:
+			return "", fmt.Errorf("Key attribute %s must be a string, number or binary", name)
+		}
+	}
+	return b.String(), nil
+}
+
+// Flush writes the buffered items, returning the number of items written
+func (b *batchWriter) Flush(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+func (b *batchWriter) flush(ctx context.Context) (int, error) {
+	if len(b.items) == 0 {
+		return 0, nil
+	}
+
+	requests := b.items
+	b.items = make([]types.WriteRequest, 0, b.config.Size)
+	b.keys = make(map[string]int, b.config.Size)
+
+	total := len(requests)
+	backoff := b.config.initialBackoff
+	for retry := 0; ; retry++ {
+		out, err := b.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{
+				b.table: requests,
+			},
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		requests = out.UnprocessedItems[b.table]
+		if len(requests) == 0 {
+			return total, nil
+		}
+
+		if retry >= b.config.MaxRetries {
+			return total - len(requests), &UnprocessedItemsError{
+				Unprocessed: len(requests),
+				Total:       total,
+				Retries:     retry,
+			}
+		}
+
+		log.Debug().
+			Int("unprocessed", len(requests)).
+			Dur("backoff", backoff).
+			Msg("Retrying unprocessed items")
+
+		// exponential backoff with jitter
+		// #nosec G404
+		if err := sleep(ctx, backoff/2+time.Duration(rand.Int63n(int64(backoff/2)+1))); err != nil {
+			return total - len(requests), err
+		}
+		backoff = min(backoff*2, b.config.maxBackoff)
+	}
+}
+
+// sleep waits for the delay unless the context is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
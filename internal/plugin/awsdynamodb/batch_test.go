@@ -0,0 +1,261 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/awsdynamodb"
+)
+
+// stubClient leaves unprocessed, at every call, the number of items found in
+// the next element of unprocessed, all the items are processed afterwards
+type stubClient struct {
+	unprocessed []int
+	calls       []int
+	requests    [][]types.WriteRequest
+	err         error
+}
+
+func (s *stubClient) BatchWriteItem(_ context.Context, params *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	requests := params.RequestItems["table"]
+	s.calls = append(s.calls, len(requests))
+	s.requests = append(s.requests, requests)
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	out := &dynamodb.BatchWriteItemOutput{}
+	if len(s.unprocessed) > 0 {
+		n := min(s.unprocessed[0], len(requests))
+		s.unprocessed = s.unprocessed[1:]
+		if n > 0 {
+			out.UnprocessedItems = map[string][]types.WriteRequest{"table": requests[:n]}
+		}
+	}
+	return out, nil
+}
+
+func newBatchWriter(t *testing.T, client *stubClient, config awsdynamodb.Batch) *awsdynamodb.BatchWriter {
+	if err := awsdynamodb.ValidateBatch(&config); err != nil {
+		t.Fatal(err)
+	}
+	return awsdynamodb.NewBatchWriter(client, "table", awsdynamodb.Key{PartitionKey: "id"}, config)
+}
+
+func item(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: id}}
+}
+
+func TestValidateBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  awsdynamodb.Batch
+		wantErr bool
+	}{
+		{name: "defaults", config: awsdynamodb.Batch{}},
+		{name: "size", config: awsdynamodb.Batch{Size: 10}},
+		{name: "size too large", config: awsdynamodb.Batch{Size: 26}, wantErr: true},
+		{name: "negative size", config: awsdynamodb.Batch{Size: -1}, wantErr: true},
+		{name: "invalid initial backoff", config: awsdynamodb.Batch{InitialBackoff: "soon"}, wantErr: true},
+		{name: "invalid max backoff", config: awsdynamodb.Batch{MaxBackoff: "later"}, wantErr: true},
+		{name: "negative initial backoff", config: awsdynamodb.Batch{InitialBackoff: "-1s"}, wantErr: true},
+		{name: "zero max backoff", config: awsdynamodb.Batch{MaxBackoff: "0s"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := awsdynamodb.ValidateBatch(&tt.config)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
+
+func TestBatchWriterRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		maxRetries  int
+		unprocessed []int
+		wantCalls   []int
+		wantWritten int
+		wantErr     *awsdynamodb.UnprocessedItemsError
+	}{
+		{
+			name:        "all processed",
+			size:        3,
+			maxRetries:  2,
+			wantCalls:   []int{3},
+			wantWritten: 3,
+		},
+		{
+			name:        "unprocessed items retried",
+			size:        3,
+			maxRetries:  2,
+			unprocessed: []int{2, 1},
+			wantCalls:   []int{3, 2, 1},
+			wantWritten: 3,
+		},
+		{
+			name:        "retries exhausted",
+			size:        4,
+			maxRetries:  2,
+			unprocessed: []int{3, 2, 2},
+			wantCalls:   []int{4, 3, 2},
+			wantWritten: 2,
+			wantErr:     &awsdynamodb.UnprocessedItemsError{Unprocessed: 2, Total: 4, Retries: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		client := &stubClient{unprocessed: tt.unprocessed}
+		w := newBatchWriter(t, client, awsdynamodb.Batch{
+			Size:           tt.size,
+			MaxRetries:     tt.maxRetries,
+			InitialBackoff: "1ms",
+			MaxBackoff:     "2ms",
+		})
+
+		var written int
+		var err error
+		for i := 0; i < tt.size; i++ {
+			written, err = w.Add(context.Background(), item(string(rune('a'+i))))
+		}
+
+		if diff := cmp.Diff(tt.wantCalls, client.calls); diff != "" {
+			t.Errorf("%s: calls: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if diff := cmp.Diff(tt.wantWritten, written); diff != "" {
+			t.Errorf("%s: written: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+
+		if tt.wantErr == nil {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			}
+			continue
+		}
+		var unprocessed *awsdynamodb.UnprocessedItemsError
+		if !errors.As(err, &unprocessed) {
+			t.Fatalf("%s: expected an UnprocessedItemsError, got %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.wantErr, unprocessed); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBatchWriterBackoffContext(t *testing.T) {
+	client := &stubClient{unprocessed: []int{1}}
+	w := newBatchWriter(t, client, awsdynamodb.Batch{Size: 2, InitialBackoff: "1h", MaxBackoff: "1h"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := w.Add(ctx, item("a")); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	written, err := w.Add(ctx, item("b"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("backoff did not stop with the context")
+	}
+	if diff := cmp.Diff(1, written); diff != "" {
+		t.Errorf("written: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestBatchWriterFlush(t *testing.T) {
+	client := &stubClient{}
+	w := newBatchWriter(t, client, awsdynamodb.Batch{Size: 10})
+
+	for _, id := range []string{"a", "b", "c"} {
+		written, err := w.Add(context.Background(), item(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written != 0 {
+			t.Errorf("unexpected write of %d items before the batch is full", written)
+		}
+	}
+
+	written, err := w.Flush(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(3, written); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	client.err = errors.New("throttled")
+	if _, err := w.Add(context.Background(), item("d")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Flush(context.Background()); err == nil {
+		t.Errorf("expected the client error")
+	}
+}
+
+func TestBatchWriterDuplicateKeys(t *testing.T) {
+	client := &stubClient{}
+	w := newBatchWriter(t, client, awsdynamodb.Batch{Size: 3})
+
+	items := []map[string]types.AttributeValue{
+		{"id": &types.AttributeValueMemberS{Value: "a"}, "v": &types.AttributeValueMemberN{Value: "1"}},
+		{"id": &types.AttributeValueMemberS{Value: "b"}, "v": &types.AttributeValueMemberN{Value: "2"}},
+		{"id": &types.AttributeValueMemberS{Value: "a"}, "v": &types.AttributeValueMemberN{Value: "3"}},
+		{"id": &types.AttributeValueMemberS{Value: "c"}, "v": &types.AttributeValueMemberN{Value: "4"}},
+	}
+	for _, item := range items {
+		if _, err := w.Add(context.Background(), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := cmp.Diff([]int{3}, client.calls); diff != "" {
+		t.Fatalf("calls: mismatch (-want +got):\n%s", diff)
+	}
+	values := make([]string, 0)
+	for _, r := range client.requests[0] {
+		values = append(values, r.PutRequest.Item["v"].(*types.AttributeValueMemberN).Value)
+	}
+	// the last write of a key replaces the pending one
+	if diff := cmp.Diff([]string{"3", "2", "4"}, values); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := w.Add(context.Background(), map[string]types.AttributeValue{"v": &types.AttributeValueMemberN{Value: "5"}}); err == nil {
+		t.Errorf("expected an error for a missing key attribute")
+	}
+}
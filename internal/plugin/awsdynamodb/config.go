@@ -23,6 +23,95 @@
 
 package awsdynamodb
 
+import "time"
+
+type Batch struct {
+	Enabled        bool   `json:"enabled"`
+	Size           int    `json:"size"`
+	MaxRetries     int    `json:"max_retries"`
+	InitialBackoff string `json:"initial_backoff"`
+	MaxBackoff     string `json:"max_backoff"`
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+type AttributeType string
+
+const (
+	StringType       AttributeType = "S"
+	NumberType       AttributeType = "N"
+	BinaryType       AttributeType = "B"
+	BoolType         AttributeType = "BOOL"
+	StringSetType    AttributeType = "SS"
+	NumberSetType    AttributeType = "NS"
+	BinarySetType    AttributeType = "BS"
+	DateType         AttributeType = "date"
+	EpochSecondsType AttributeType = "epoch_seconds"
+	EpochMillisType  AttributeType = "epoch_millis"
+)
+
+type WriteMode string
+
+const (
+	PutMode            WriteMode = "put"
+	PutIfNotExistsMode WriteMode = "put_if_not_exists"
+	UpdateMode         WriteMode = "update"
+)
+
+type Key struct {
+	PartitionKey string `json:"partition_key"`
+	SortKey      string `json:"sort_key"`
+}
+
+type TTL struct {
+	Attribute string `json:"attribute"`
+	Duration  string `json:"duration"`
+	duration  time.Duration
+}
+
+type KeyAttribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type GlobalSecondaryIndex struct {
+	Name             string       `json:"name"`
+	PartitionKey     KeyAttribute `json:"partition_key"`
+	SortKey          KeyAttribute `json:"sort_key"`
+	Projection       string       `json:"projection"`
+	NonKeyAttributes []string     `json:"non_key_attributes"`
+	ReadCapacity     int64        `json:"read_capacity"`
+	WriteCapacity    int64        `json:"write_capacity"`
+}
+
+type Schema struct {
+	Create                 bool                   `json:"create"`
+	PartitionKey           KeyAttribute           `json:"partition_key"`
+	SortKey                KeyAttribute           `json:"sort_key"`
+	BillingMode            string                 `json:"billing_mode"`
+	ReadCapacity           int64                  `json:"read_capacity"`
+	WriteCapacity          int64                  `json:"write_capacity"`
+	GlobalSecondaryIndexes []GlobalSecondaryIndex `json:"global_secondary_indexes"`
+	WaitTimeout            string                 `json:"wait_timeout"`
+	waitTimeout            time.Duration
+}
+
+type Credentials struct {
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	SessionToken    string `json:"session_token"`
+	Profile         string `json:"profile"`
+}
+
 type Config struct {
-	Table string `json:"table"`
+	Table       string                   `json:"table"`
+	Region      string                   `json:"region"`
+	Endpoint    string                   `json:"endpoint"`
+	Credentials Credentials              `json:"credentials"`
+	Types       map[string]AttributeType `json:"types"`
+	Mode        WriteMode                `json:"mode"`
+	Key         Key                      `json:"key"`
+	TTL         TTL                      `json:"ttl"`
+	Schema      Schema                   `json:"schema"`
+	Batch       Batch                    `json:"batch"`
 }
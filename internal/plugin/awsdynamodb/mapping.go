@@ -0,0 +1,234 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// MarshalItem converts a JSON object into a DynamoDB item.
+// Top level attributes listed in mapping are written with the given type,
+// the others follow the attributevalue default rules with numbers kept as N.
+func MarshalItem(val []byte, mapping map[string]AttributeType) (map[string]types.AttributeValue, error) {
+	var jsonMap map[string]interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(val))
+	decoder.UseNumber()
+	if err := decoder.Decode(&jsonMap); err != nil {
+		return nil, err
+	}
+
+	item := make(map[string]types.AttributeValue, len(jsonMap))
+	for name, value := range jsonMap {
+		var av types.AttributeValue
+		var err error
+
+		if t, ok := mapping[name]; ok && value != nil {
+			av, err = convertAttribute(value, t)
+		} else {
+			av, err = attributevalue.Marshal(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = av
+	}
+
+	return item, nil
+}
+
+func validateTypes(mapping map[string]AttributeType) error {
+	for name, t := range mapping {
+		switch t {
+		case StringType, NumberType, BinaryType, BoolType,
+			StringSetType, NumberSetType, BinarySetType,
+			DateType, EpochSecondsType, EpochMillisType:
+		default:
+			return fmt.Errorf("Unknown type %s for attribute %s", t, name)
+		}
+	}
+	return nil
+}
+
+func convertAttribute(value interface{}, t AttributeType) (types.AttributeValue, error) {
+	switch t {
+	case StringType:
+		s, err := toString(value)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberS{Value: s}, nil
+	case NumberType:
+		n, err := toNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberN{Value: n}, nil
+	case BinaryType:
+		b, err := toBinary(value)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberB{Value: b}, nil
+	case BoolType:
+		switch v := value.(type) {
+		case bool:
+			return &types.AttributeValueMemberBOOL{Value: v}, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberBOOL{Value: b}, nil
+		}
+		return nil, fmt.Errorf("cannot convert %T to BOOL", value)
+	case StringSetType:
+		ss, err := toSet(value, toString)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberSS{Value: ss}, nil
+	case NumberSetType:
+		ns, err := toSet(value, toNumber)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AttributeValueMemberNS{Value: ns}, nil
+	case BinarySetType:
+		encoded, err := toSet(value, toString)
+		if err != nil {
+			return nil, err
+		}
+		bs := make([][]byte, len(encoded))
+		for i, e := range encoded {
+			if bs[i], err = toBinary(e); err != nil {
+				return nil, err
+			}
+		}
+		return &types.AttributeValueMemberBS{Value: bs}, nil
+	case DateType, EpochSecondsType, EpochMillisType:
+		d, err := toTime(value)
+		if err != nil {
+			return nil, err
+		}
+		switch t {
+		case EpochSecondsType:
+			return &types.AttributeValueMemberN{Value: strconv.FormatInt(d.Unix(), 10)}, nil
+		case EpochMillisType:
+			return &types.AttributeValueMemberN{Value: strconv.FormatInt(d.UnixMilli(), 10)}, nil
+		default:
+			return &types.AttributeValueMemberS{Value: d.UTC().Format(time.RFC3339Nano)}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown type %s", t)
+}
+
+func toString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func toNumber(value interface{}) (string, error) {
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return "", fmt.Errorf("cannot convert %T to N", value)
+	}
+	if _, ok := new(big.Float).SetString(s); !ok {
+		return "", fmt.Errorf("%q is not a number", s)
+	}
+	return s, nil
+}
+
+func toBinary(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to B", value)
+	}
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// toSet converts an array into a DynamoDB set, dropping duplicates
+// since sets must contain unique elements
+func toSet(value interface{}, convert func(interface{}) (string, error)) ([]string, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot convert %T to a set", value)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("sets cannot be empty")
+	}
+
+	set := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, e := range values {
+		s, err := convert(e)
+		if err != nil {
+			return nil, err
+		}
+		if seen[s] {
+			continue
+		}
+		seen[s] = true
+		set = append(set, s)
+	}
+	return set, nil
+}
+
+func toTime(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("cannot convert %T to a date", value)
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not an ISO-8601 date", s)
+}
@@ -0,0 +1,174 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/awsdynamodb"
+)
+
+// describe flattens an attribute value into TYPE:value for comparison
+func describe(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S:" + v.Value
+	case *types.AttributeValueMemberN:
+		return "N:" + v.Value
+	case *types.AttributeValueMemberB:
+		return "B:" + string(v.Value)
+	case *types.AttributeValueMemberBOOL:
+		return fmt.Sprintf("BOOL:%t", v.Value)
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	case *types.AttributeValueMemberSS:
+		return "SS:" + strings.Join(v.Value, ",")
+	case *types.AttributeValueMemberNS:
+		return "NS:" + strings.Join(v.Value, ",")
+	case *types.AttributeValueMemberBS:
+		values := make([]string, len(v.Value))
+		for i, b := range v.Value {
+			values[i] = string(b)
+		}
+		return "BS:" + strings.Join(values, ",")
+	case *types.AttributeValueMemberL:
+		values := make([]string, len(v.Value))
+		for i, e := range v.Value {
+			values[i] = describe(e)
+		}
+		return "L:[" + strings.Join(values, ",") + "]"
+	case *types.AttributeValueMemberM:
+		values := make([]string, 0, len(v.Value))
+		for k, e := range v.Value {
+			values = append(values, k+"="+describe(e))
+		}
+		sort.Strings(values)
+		return "M:{" + strings.Join(values, ",") + "}"
+	}
+	return fmt.Sprintf("%T", av)
+}
+
+func TestMarshalItem(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString([]byte("jr"))
+
+	testCases := []struct {
+		name    string
+		value   string
+		mapping map[string]awsdynamodb.AttributeType
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "default_mapping",
+			value: `{"id":"a","n":12345678901234567890,"b":true,"z":null,"l":[1,"x"],"m":{"k":1.5}}`,
+			want: map[string]string{
+				"id": "S:a",
+				"n":  "N:12345678901234567890",
+				"b":  "BOOL:true",
+				"z":  "NULL",
+				"l":  "L:[N:1,S:x]",
+				"m":  "M:{k=N:1.5}",
+			},
+		},
+		{
+			name:  "typed_mapping",
+			value: `{"price":"10.5","code":42,"avatar":"` + b64 + `","tags":["a","b","a"],"scores":[1,2],"flag":"true"}`,
+			mapping: map[string]awsdynamodb.AttributeType{
+				"price":  awsdynamodb.NumberType,
+				"code":   awsdynamodb.StringType,
+				"avatar": awsdynamodb.BinaryType,
+				"tags":   awsdynamodb.StringSetType,
+				"scores": awsdynamodb.NumberSetType,
+				"flag":   awsdynamodb.BoolType,
+			},
+			want: map[string]string{
+				"price":  "N:10.5",
+				"code":   "S:42",
+				"avatar": "B:jr",
+				"tags":   "SS:a,b",
+				"scores": "NS:1,2",
+				"flag":   "BOOL:true",
+			},
+		},
+		{
+			name:  "dates",
+			value: `{"a":"2024-08-30T14:56:51+02:00","b":"2024-08-30T12:56:51Z","c":"2024-08-30"}`,
+			mapping: map[string]awsdynamodb.AttributeType{
+				"a": awsdynamodb.DateType,
+				"b": awsdynamodb.EpochSecondsType,
+				"c": awsdynamodb.EpochMillisType,
+			},
+			want: map[string]string{
+				"a": "S:2024-08-30T12:56:51Z",
+				"b": "N:1725022611",
+				"c": "N:1724976000000",
+			},
+		},
+		{
+			name:  "not_a_number",
+			value: `{"price":"ten"}`,
+			mapping: map[string]awsdynamodb.AttributeType{
+				"price": awsdynamodb.NumberType,
+			},
+			wantErr: true,
+		},
+		{
+			name:  "empty_set",
+			value: `{"tags":[]}`,
+			mapping: map[string]awsdynamodb.AttributeType{
+				"tags": awsdynamodb.StringSetType,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			item, err := awsdynamodb.MarshalItem([]byte(tc.value), tc.mapping)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string, len(item))
+			for k, v := range item {
+				got[k] = describe(v)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s: mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -27,13 +27,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
+	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -48,6 +50,7 @@ type Plugin struct {
 	configuration Config
 
 	client *dynamodb.Client
+	batch  *batchWriter
 }
 
 func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
@@ -61,34 +64,100 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 		return fmt.Errorf("Table is mandatory")
 	}
 
-	awsConfig, err := awsconfig.LoadDefaultConfig(ctx)
+	if config.Batch.Enabled {
+		if err := validateBatch(&config.Batch); err != nil {
+			return err
+		}
+	}
+
+	if err := validateTypes(config.Types); err != nil {
+		return err
+	}
+
+	if err := validateWriteMode(&config); err != nil {
+		return err
+	}
+
+	if config.Schema.Create {
+		if err := validateSchema(&config.Schema); err != nil {
+			return err
+		}
+		if config.Key.PartitionKey == "" {
+			config.Key = Key{
+				PartitionKey: config.Schema.PartitionKey.Name,
+				SortKey:      config.Schema.SortKey.Name,
+			}
+		}
+	}
+
+	opts := make([]func(*awsconfig.LoadOptions) error, 0)
+	if config.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(config.Region))
+	}
+	if config.Credentials.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(config.Credentials.Profile))
+	}
+	if config.Credentials.AccessKeyID != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.Credentials.AccessKeyID,
+				config.Credentials.SecretAccessKey,
+				config.Credentials.SessionToken)))
+	}
+
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return err
 	}
-	client := dynamodb.NewFromConfig(awsConfig)
+	client := dynamodb.NewFromConfig(awsConfig, func(o *dynamodb.Options) {
+		// e.g. DynamoDB Local
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+
+	if config.Schema.Create {
+		if err := createTable(ctx, client, config.Table, config.Schema); err != nil {
+			return err
+		}
+	}
+
+	// the key is needed to build conditions and updates, and to dedupe batches
+	if (config.Mode != PutMode || config.Batch.Enabled) && config.Key.PartitionKey == "" {
+		config.Key, err = describeKey(ctx, client, config.Table)
+		if err != nil {
+			return err
+		}
+	}
 
 	p.client = client
 	p.configuration = config
+
+	if config.Batch.Enabled {
+		p.batch = newBatchWriter(client, config.Table, config.Key, config.Batch)
+	}
 	return nil
 }
 
 func (p *Plugin) Produce(_ []byte, val []byte, headers map[string]string) (*jrpc.ProduceResponse, error) {
 
-	var jsonMap map[string]interface{}
-	if err := json.Unmarshal(val, &jsonMap); err != nil {
-		return nil, err
-	}
-
-	item, err := attributevalue.MarshalMap(jsonMap)
+	item, err := MarshalItem(val, p.configuration.Types)
 	if err != nil {
 		return nil, err
 	}
+	stampTTL(item, p.configuration.TTL, time.Now())
+
+	if p.batch != nil {
+		written, err := p.batch.Add(context.Background(), item)
+		if err != nil {
+			return nil, err
+		}
+		return &jrpc.ProduceResponse{
+			Bytes:   uint64(len(val)),
+			Message: batchMessage(written),
+		}, nil
+	}
 
-	_, err = p.client.PutItem(context.Background(), &dynamodb.PutItemInput{
-		TableName: aws.String(p.configuration.Table),
-		Item:      item,
-	})
-	if err != nil {
+	if err := p.write(context.Background(), item); err != nil {
 		return nil, err
 	}
 
@@ -98,6 +167,20 @@ func (p *Plugin) Produce(_ []byte, val []byte, headers map[string]string) (*jrpc
 
 }
 
-func (p *Plugin) Close(_ context.Context) error {
+func batchMessage(written int) string {
+	if written == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d items written", written)
+}
+
+func (p *Plugin) Close(ctx context.Context) error {
+	if p.batch != nil {
+		written, err := p.batch.Flush(ctx)
+		if err != nil {
+			return err
+		}
+		log.Debug().Int("written", written).Msg("Flushed pending items")
+	}
 	return nil
 }
@@ -0,0 +1,210 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/rs/zerolog/log"
+)
+
+const defaultWaitTimeout = 2 * time.Minute
+
+func validateSchema(config *Schema) error {
+	var err error
+
+	if config.PartitionKey.Name == "" {
+		return fmt.Errorf("Schema partition key is mandatory")
+	}
+
+	switch types.BillingMode(config.BillingMode) {
+	case "":
+		config.BillingMode = string(types.BillingModePayPerRequest)
+	case types.BillingModePayPerRequest:
+	case types.BillingModeProvisioned:
+		if config.ReadCapacity <= 0 || config.WriteCapacity <= 0 {
+			return fmt.Errorf("Read and write capacity are mandatory with provisioned billing mode")
+		}
+	default:
+		return fmt.Errorf("Unknown billing mode: %s", config.BillingMode)
+	}
+
+	config.waitTimeout = defaultWaitTimeout
+	if config.WaitTimeout != "" {
+		if config.waitTimeout, err = time.ParseDuration(config.WaitTimeout); err != nil {
+			return err
+		}
+	}
+
+	for _, gsi := range config.GlobalSecondaryIndexes {
+		if gsi.Name == "" || gsi.PartitionKey.Name == "" {
+			return fmt.Errorf("Global secondary index name and partition key are mandatory")
+		}
+	}
+
+	return nil
+}
+
+// BuildCreateTable creates the CreateTable request described by the schema
+func BuildCreateTable(table string, schema Schema) (*dynamodb.CreateTableInput, error) {
+	definitions := make([]types.AttributeDefinition, 0)
+	defined := make(map[string]types.ScalarAttributeType)
+
+	define := func(attribute KeyAttribute) error {
+		t := types.ScalarAttributeType(attribute.Type)
+		if t == "" {
+			t = types.ScalarAttributeTypeS
+		}
+		switch t {
+		case types.ScalarAttributeTypeS, types.ScalarAttributeTypeN, types.ScalarAttributeTypeB:
+		default:
+			return fmt.Errorf("Unknown key type %s for attribute %s", attribute.Type, attribute.Name)
+		}
+
+		if previous, ok := defined[attribute.Name]; ok {
+			if previous != t {
+				return fmt.Errorf("Attribute %s defined as both %s and %s", attribute.Name, previous, t)
+			}
+			return nil
+		}
+		defined[attribute.Name] = t
+		definitions = append(definitions, types.AttributeDefinition{
+			AttributeName: aws.String(attribute.Name),
+			AttributeType: t,
+		})
+		return nil
+	}
+
+	keySchema := func(partitionKey KeyAttribute, sortKey KeyAttribute) ([]types.KeySchemaElement, error) {
+		if err := define(partitionKey); err != nil {
+			return nil, err
+		}
+		elements := []types.KeySchemaElement{{
+			AttributeName: aws.String(partitionKey.Name),
+			KeyType:       types.KeyTypeHash,
+		}}
+		if sortKey.Name != "" {
+			if err := define(sortKey); err != nil {
+				return nil, err
+			}
+			elements = append(elements, types.KeySchemaElement{
+				AttributeName: aws.String(sortKey.Name),
+				KeyType:       types.KeyTypeRange,
+			})
+		}
+		return elements, nil
+	}
+
+	provisioned := types.BillingMode(schema.BillingMode) == types.BillingModeProvisioned
+
+	tableKey, err := keySchema(schema.PartitionKey, schema.SortKey)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		KeySchema:   tableKey,
+		BillingMode: types.BillingMode(schema.BillingMode),
+	}
+	if provisioned {
+		input.ProvisionedThroughput = &types.ProvisionedThroughput{
+			ReadCapacityUnits:  aws.Int64(schema.ReadCapacity),
+			WriteCapacityUnits: aws.Int64(schema.WriteCapacity),
+		}
+	}
+
+	for _, gsi := range schema.GlobalSecondaryIndexes {
+		indexKey, err := keySchema(gsi.PartitionKey, gsi.SortKey)
+		if err != nil {
+			return nil, err
+		}
+
+		projection := types.ProjectionType(gsi.Projection)
+		if projection == "" {
+			projection = types.ProjectionTypeAll
+		}
+
+		index := types.GlobalSecondaryIndex{
+			IndexName: aws.String(gsi.Name),
+			KeySchema: indexKey,
+			Projection: &types.Projection{
+				ProjectionType:   projection,
+				NonKeyAttributes: gsi.NonKeyAttributes,
+			},
+		}
+		if provisioned {
+			// indexes default to the table capacity
+			index.ProvisionedThroughput = &types.ProvisionedThroughput{
+				ReadCapacityUnits:  aws.Int64(schema.ReadCapacity),
+				WriteCapacityUnits: aws.Int64(schema.WriteCapacity),
+			}
+			if gsi.ReadCapacity > 0 {
+				index.ProvisionedThroughput.ReadCapacityUnits = aws.Int64(gsi.ReadCapacity)
+			}
+			if gsi.WriteCapacity > 0 {
+				index.ProvisionedThroughput.WriteCapacityUnits = aws.Int64(gsi.WriteCapacity)
+			}
+		}
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, index)
+	}
+
+	input.AttributeDefinitions = definitions
+	return input, nil
+}
+
+// createTable creates the table if it does not exist and waits for it to become active
+func createTable(ctx context.Context, client *dynamodb.Client, table string, schema Schema) error {
+	_, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(table),
+	})
+	if err == nil {
+		return nil
+	}
+
+	var notFound *types.ResourceNotFoundException
+	if !errors.As(err, &notFound) {
+		return err
+	}
+
+	input, err := BuildCreateTable(table, schema)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Str("table", table).Msg("Creating table")
+	if _, err := client.CreateTable(ctx, input); err != nil {
+		return err
+	}
+
+	return dynamodb.NewTableExistsWaiter(client).Wait(ctx,
+		&dynamodb.DescribeTableInput{TableName: aws.String(table)},
+		schema.waitTimeout)
+}
@@ -0,0 +1,128 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/awsdynamodb"
+)
+
+func TestBuildCreateTable(t *testing.T) {
+	testCases := []struct {
+		name            string
+		schema          awsdynamodb.Schema
+		wantDefinitions map[string]string
+		wantKey         map[string]string
+		wantIndexes     []string
+		wantErr         bool
+	}{
+		{
+			name: "partition_and_sort_key",
+			schema: awsdynamodb.Schema{
+				PartitionKey: awsdynamodb.KeyAttribute{Name: "id"},
+				SortKey:      awsdynamodb.KeyAttribute{Name: "ts", Type: "N"},
+				BillingMode:  "PAY_PER_REQUEST",
+			},
+			wantDefinitions: map[string]string{"id": "S", "ts": "N"},
+			wantKey:         map[string]string{"id": "HASH", "ts": "RANGE"},
+		},
+		{
+			name: "gsi_sharing_attributes",
+			schema: awsdynamodb.Schema{
+				PartitionKey: awsdynamodb.KeyAttribute{Name: "id", Type: "S"},
+				BillingMode:  "PROVISIONED",
+				ReadCapacity: 5, WriteCapacity: 5,
+				GlobalSecondaryIndexes: []awsdynamodb.GlobalSecondaryIndex{
+					{
+						Name:         "by_user",
+						PartitionKey: awsdynamodb.KeyAttribute{Name: "user", Type: "S"},
+						SortKey:      awsdynamodb.KeyAttribute{Name: "id", Type: "S"},
+					},
+				},
+			},
+			wantDefinitions: map[string]string{"id": "S", "user": "S"},
+			wantKey:         map[string]string{"id": "HASH"},
+			wantIndexes:     []string{"by_user"},
+		},
+		{
+			name: "conflicting_types",
+			schema: awsdynamodb.Schema{
+				PartitionKey: awsdynamodb.KeyAttribute{Name: "id", Type: "S"},
+				GlobalSecondaryIndexes: []awsdynamodb.GlobalSecondaryIndex{
+					{
+						Name:         "by_id",
+						PartitionKey: awsdynamodb.KeyAttribute{Name: "id", Type: "N"},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input, err := awsdynamodb.BuildCreateTable("table", tc.schema)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			definitions := make(map[string]string)
+			for _, d := range input.AttributeDefinitions {
+				definitions[aws.StringValue(d.AttributeName)] = string(d.AttributeType)
+			}
+			if diff := cmp.Diff(tc.wantDefinitions, definitions); diff != "" {
+				t.Errorf("%s: mismatch definitions (-want +got):\n%s", tc.name, diff)
+			}
+
+			key := make(map[string]string)
+			for _, k := range input.KeySchema {
+				key[aws.StringValue(k.AttributeName)] = string(k.KeyType)
+			}
+			if diff := cmp.Diff(tc.wantKey, key); diff != "" {
+				t.Errorf("%s: mismatch key schema (-want +got):\n%s", tc.name, diff)
+			}
+
+			var indexes []string
+			for _, gsi := range input.GlobalSecondaryIndexes {
+				indexes = append(indexes, aws.StringValue(gsi.IndexName))
+				if input.BillingMode == types.BillingModeProvisioned && gsi.ProvisionedThroughput == nil {
+					t.Errorf("%s: missing index throughput", tc.name)
+				}
+			}
+			if diff := cmp.Diff(tc.wantIndexes, indexes); diff != "" {
+				t.Errorf("%s: mismatch indexes (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrConditionalCheckFailed is returned when a conditional write is rejected,
+// e.g. when put_if_not_exists finds an item with the same key
+var ErrConditionalCheckFailed = errors.New("conditional check failed")
+
+func validateWriteMode(config *Config) error {
+	switch config.Mode {
+	case "":
+		config.Mode = PutMode
+	case PutMode:
+	case PutIfNotExistsMode, UpdateMode:
+		if config.Batch.Enabled {
+			return fmt.Errorf("Mode %s cannot be used with batch writes", config.Mode)
+		}
+	default:
+		return fmt.Errorf("Unknown mode: %s", config.Mode)
+	}
+
+	if config.TTL.Attribute != "" {
+		var err error
+		if config.TTL.duration, err = time.ParseDuration(config.TTL.Duration); err != nil {
+			return fmt.Errorf("Invalid TTL duration: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// describeKey reads the key attributes from the table definition
+func describeKey(ctx context.Context, client *dynamodb.Client, table string) (Key, error) {
+	key := Key{}
+
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(table),
+	})
+	if err != nil {
+		return key, err
+	}
+
+	for _, k := range out.Table.KeySchema {
+		switch k.KeyType {
+		case types.KeyTypeHash:
+			key.PartitionKey = aws.StringValue(k.AttributeName)
+		case types.KeyTypeRange:
+			key.SortKey = aws.StringValue(k.AttributeName)
+		}
+	}
+	return key, nil
+}
+
+// stampTTL sets the TTL attribute as an epoch in seconds
+func stampTTL(item map[string]types.AttributeValue, ttl TTL, now time.Time) {
+	if ttl.Attribute == "" {
+		return
+	}
+	item[ttl.Attribute] = &types.AttributeValueMemberN{
+		Value: strconv.FormatInt(now.Add(ttl.duration).Unix(), 10),
+	}
+}
+
+func (k Key) names() []string {
+	if k.SortKey == "" {
+		return []string{k.PartitionKey}
+	}
+	return []string{k.PartitionKey, k.SortKey}
+}
+
+// BuildPutIfNotExists creates a PutItem request that fails if an item
+// with the same key already exists
+func BuildPutIfNotExists(table string, item map[string]types.AttributeValue, key Key) *dynamodb.PutItemInput {
+	conditions := make([]string, 0, 2)
+	names := make(map[string]string, 2)
+	for i, name := range key.names() {
+		placeholder := fmt.Sprintf("#k%d", i)
+		conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", placeholder))
+		names[placeholder] = name
+	}
+
+	return &dynamodb.PutItemInput{
+		TableName:                aws.String(table),
+		Item:                     item,
+		ConditionExpression:      aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames: names,
+	}
+}
+
+// BuildUpdate creates an UpdateItem request setting every non key attribute of the item
+func BuildUpdate(table string, item map[string]types.AttributeValue, key Key) (*dynamodb.UpdateItemInput, error) {
+	keyItem := make(map[string]types.AttributeValue, 2)
+	for _, name := range key.names() {
+		v, ok := item[name]
+		if !ok {
+			return nil, fmt.Errorf("key attribute %s not found in value", name)
+		}
+		keyItem[name] = v
+	}
+
+	attributes := make([]string, 0, len(item))
+	for name := range item {
+		if _, isKey := keyItem[name]; !isKey {
+			attributes = append(attributes, name)
+		}
+	}
+	sort.Strings(attributes)
+
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key:       keyItem,
+	}
+	if len(attributes) == 0 {
+		return input, nil
+	}
+
+	sets := make([]string, len(attributes))
+	names := make(map[string]string, len(attributes))
+	values := make(map[string]types.AttributeValue, len(attributes))
+	for i, name := range attributes {
+		sets[i] = fmt.Sprintf("#a%d = :v%d", i, i)
+		names[fmt.Sprintf("#a%d", i)] = name
+		values[fmt.Sprintf(":v%d", i)] = item[name]
+	}
+
+	input.UpdateExpression = aws.String("SET " + strings.Join(sets, ", "))
+	input.ExpressionAttributeNames = names
+	input.ExpressionAttributeValues = values
+	return input, nil
+}
+
+func (p *Plugin) write(ctx context.Context, item map[string]types.AttributeValue) error {
+	var err error
+
+	switch p.configuration.Mode {
+	case PutIfNotExistsMode:
+		_, err = p.client.PutItem(ctx, BuildPutIfNotExists(p.configuration.Table, item, p.configuration.Key))
+	case UpdateMode:
+		var input *dynamodb.UpdateItemInput
+		if input, err = BuildUpdate(p.configuration.Table, item, p.configuration.Key); err != nil {
+			return err
+		}
+		_, err = p.client.UpdateItem(ctx, input)
+	default:
+		_, err = p.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(p.configuration.Table),
+			Item:      item,
+		})
+	}
+
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return fmt.Errorf("%w: %s", ErrConditionalCheckFailed, ccf.ErrorMessage())
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+//go:build awsdynamodb
+// +build awsdynamodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package awsdynamodb_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/awsdynamodb"
+)
+
+func TestBuildPutIfNotExists(t *testing.T) {
+	item, err := awsdynamodb.MarshalItem([]byte(`{"pk":"a","sk":1,"v":"x"}`), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := awsdynamodb.BuildPutIfNotExists("table", item, awsdynamodb.Key{PartitionKey: "pk", SortKey: "sk"})
+
+	if diff := cmp.Diff("attribute_not_exists(#k0) AND attribute_not_exists(#k1)",
+		aws.StringValue(input.ConditionExpression)); diff != "" {
+		t.Errorf("mismatch condition (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]string{"#k0": "pk", "#k1": "sk"}, input.ExpressionAttributeNames); diff != "" {
+		t.Errorf("mismatch names (-want +got):\n%s", diff)
+	}
+}
+
+func TestBuildUpdate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		value      string
+		key        awsdynamodb.Key
+		wantKey    map[string]string
+		wantExpr   string
+		wantNames  map[string]string
+		wantValues map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "partition_key",
+			value:      `{"id":"a","b":2,"a":"x"}`,
+			key:        awsdynamodb.Key{PartitionKey: "id"},
+			wantKey:    map[string]string{"id": "S:a"},
+			wantExpr:   "SET #a0 = :v0, #a1 = :v1",
+			wantNames:  map[string]string{"#a0": "a", "#a1": "b"},
+			wantValues: map[string]string{":v0": "S:x", ":v1": "N:2"},
+		},
+		{
+			name:    "key_only",
+			value:   `{"id":"a","sk":"b"}`,
+			key:     awsdynamodb.Key{PartitionKey: "id", SortKey: "sk"},
+			wantKey: map[string]string{"id": "S:a", "sk": "S:b"},
+		},
+		{
+			name:    "missing_key",
+			value:   `{"a":"x"}`,
+			key:     awsdynamodb.Key{PartitionKey: "id"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			item, err := awsdynamodb.MarshalItem([]byte(tc.value), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			input, err := awsdynamodb.BuildUpdate("table", item, tc.key)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotKey := make(map[string]string)
+			for k, v := range input.Key {
+				gotKey[k] = describe(v)
+			}
+			if diff := cmp.Diff(tc.wantKey, gotKey); diff != "" {
+				t.Errorf("%s: mismatch key (-want +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantExpr, aws.StringValue(input.UpdateExpression)); diff != "" {
+				t.Errorf("%s: mismatch expression (-want +got):\n%s", tc.name, diff)
+			}
+			if diff := cmp.Diff(tc.wantNames, input.ExpressionAttributeNames); diff != "" {
+				t.Errorf("%s: mismatch names (-want +got):\n%s", tc.name, diff)
+			}
+
+			var gotValues map[string]string
+			if input.ExpressionAttributeValues != nil {
+				gotValues = make(map[string]string)
+				for k, v := range input.ExpressionAttributeValues {
+					gotValues[k] = describe(v)
+				}
+			}
+			if diff := cmp.Diff(tc.wantValues, gotValues); diff != "" {
+				t.Errorf("%s: mismatch values (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
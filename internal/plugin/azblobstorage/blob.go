@@ -0,0 +1,172 @@
+//go:build azblobstorage
+// +build azblobstorage
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azblobstorage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/google/uuid"
+)
+
+// MaxTags is the maximum number of index tags of a blob
+const MaxTags = 10
+
+// NameData is the data available to the blob name template
+type NameData struct {
+	Key     string
+	UUID    string
+	Time    time.Time
+	Value   map[string]any
+	Headers map[string]string
+}
+
+func validateBlob(config *Config) error {
+	if config.AccessTier != "" {
+		if !slices.Contains(blob.PossibleAccessTierValues(), blob.AccessTier(config.AccessTier)) {
+			return fmt.Errorf("Unknown access tier: %s", config.AccessTier)
+		}
+		if config.Mode == AppendMode {
+			return fmt.Errorf("Access tier is not supported in %s mode", AppendMode)
+		}
+	}
+
+	if len(config.Tags.Static) > MaxTags {
+		return fmt.Errorf("At most %d tags are allowed", MaxTags)
+	}
+
+	// append and block blobs hold many records, their properties are set once
+	if config.Mode != BlobMode {
+		if config.Metadata.FromHeaders {
+			return fmt.Errorf("Metadata from headers is not supported in %s mode", config.Mode)
+		}
+		if config.Tags.FromHeaders {
+			return fmt.Errorf("Tags from headers are not supported in %s mode", config.Mode)
+		}
+	}
+
+	return nil
+}
+
+// ParseNameTemplate parses the blob name template, fields missing from the
+// record make the rendering fail instead of producing "<no value>"
+func ParseNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("name").Option("missingkey=error").Parse(text)
+}
+
+// BlobName builds the name of the blob for the given record, when no template
+// is set the key is used or a random UUID if the key is empty
+func BlobName(tmpl *template.Template, prefix string, k []byte, v []byte, headers map[string]string) (string, error) {
+	var key string
+	if len(k) != 0 && strings.ToLower(string(k)) != "null" {
+		key = string(k)
+	}
+
+	if tmpl == nil {
+		if key == "" {
+			key = uuid.New().String()
+		}
+		return prefix + key, nil
+	}
+
+	data := NameData{
+		Key:     key,
+		UUID:    uuid.New().String(),
+		Time:    time.Now().UTC(),
+		Headers: headers,
+	}
+	// values that are not JSON objects are simply not available to the template
+	_ = json.Unmarshal(v, &data.Value)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("Name template rendered an empty blob name")
+	}
+	return prefix + buf.String(), nil
+}
+
+// MetadataName turns a header name into a valid metadata name, metadata names
+// must be valid C# identifiers
+func MetadataName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			b.WriteRune(r)
+		case unicode.IsDigit(r):
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+func (p *Plugin) metadata(headers map[string]string) map[string]*string {
+	values := p.configuration.Metadata.Values(headers)
+	metadata := make(map[string]*string, len(values))
+	for k, v := range values {
+		metadata[MetadataName(k)] = &v
+	}
+	return metadata
+}
+
+func (p *Plugin) httpHeaders() *blob.HTTPHeaders {
+	if p.configuration.ContentType == "" && p.configuration.ContentEncoding == "" {
+		return nil
+	}
+
+	headers := blob.HTTPHeaders{}
+	if p.configuration.ContentType != "" {
+		headers.BlobContentType = &p.configuration.ContentType
+	}
+	if p.configuration.ContentEncoding != "" {
+		headers.BlobContentEncoding = &p.configuration.ContentEncoding
+	}
+	return &headers
+}
+
+func (p *Plugin) accessTier() *blob.AccessTier {
+	if p.configuration.AccessTier == "" {
+		return nil
+	}
+	tier := blob.AccessTier(p.configuration.AccessTier)
+	return &tier
+}
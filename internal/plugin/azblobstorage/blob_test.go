@@ -0,0 +1,175 @@
+//go:build azblobstorage
+// +build azblobstorage
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azblobstorage_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/mapping"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/azblobstorage"
+)
+
+func TestBlobName(t *testing.T) {
+	today := time.Now().UTC().Format("2006/01/02")
+
+	tests := []struct {
+		name     string
+		template string
+		prefix   string
+		key      string
+		value    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:  "key",
+			key:   "k1",
+			value: `{"id":"1"}`,
+			want:  "k1",
+		},
+		{
+			name:   "key with prefix",
+			prefix: "jr/",
+			key:    "k1",
+			value:  `{"id":"1"}`,
+			want:   "jr/k1",
+		},
+		{
+			name:     "date partitions and fields",
+			template: `{{.Time.Format "2006/01/02"}}/{{.Value.tenant}}/{{.Value.id}}.json`,
+			prefix:   "jr/",
+			value:    `{"id":"1","tenant":"acme"}`,
+			want:     "jr/" + today + "/acme/1.json",
+		},
+		{
+			name:     "headers",
+			template: `{{.Headers.source}}/{{.Key}}`,
+			key:      "k1",
+			value:    `"not an object"`,
+			want:     "jr/k1",
+		},
+		{
+			name:     "missing field",
+			template: `{{.Value.tenant}}/{{.Value.missing}}`,
+			value:    `{"id":"1","tenant":"acme"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "value not an object",
+			template: `{{.Value.id}}`,
+			value:    `"not an object"`,
+			wantErr:  true,
+		},
+		{
+			name:     "empty name",
+			template: `{{if false}}x{{end}}`,
+			value:    `{"id":"1"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := azblobstorage.ParseNameTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got, err := azblobstorage.BlobName(tmpl, tt.prefix, []byte(tt.key), []byte(tt.value), map[string]string{"source": "jr"})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestMetadataName(t *testing.T) {
+	tests := map[string]string{
+		"source":       "source",
+		"Content-Type": "Content_Type",
+		"x.trace.id":   "x_trace_id",
+		"1st":          "_1st",
+	}
+
+	for in, want := range tests {
+		if diff := cmp.Diff(want, azblobstorage.MetadataName(in)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", in, diff)
+		}
+	}
+}
+
+func TestValidateBlob(t *testing.T) {
+	fromHeaders := mapping.Mapping{FromHeaders: true}
+
+	tests := []struct {
+		name    string
+		config  azblobstorage.Config
+		wantErr bool
+	}{
+		{
+			name:   "blob mode from headers",
+			config: azblobstorage.Config{Mode: azblobstorage.BlobMode, Metadata: fromHeaders, Tags: fromHeaders},
+		},
+		{
+			name:   "block mode static",
+			config: azblobstorage.Config{Mode: azblobstorage.BlockMode, Metadata: mapping.Mapping{Static: map[string]string{"a": "b"}}, AccessTier: "Cool"},
+		},
+		{
+			name:    "append mode metadata from headers",
+			config:  azblobstorage.Config{Mode: azblobstorage.AppendMode, Metadata: fromHeaders},
+			wantErr: true,
+		},
+		{
+			name:    "block mode tags from headers",
+			config:  azblobstorage.Config{Mode: azblobstorage.BlockMode, Tags: fromHeaders},
+			wantErr: true,
+		},
+		{
+			name:    "append mode access tier",
+			config:  azblobstorage.Config{Mode: azblobstorage.AppendMode, AccessTier: "Cool"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown access tier",
+			config:  azblobstorage.Config{Mode: azblobstorage.BlobMode, AccessTier: "Warm"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := azblobstorage.ValidateBlob(&tt.config)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+//go:build azblobstorage
+// +build azblobstorage
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azblobstorage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/jrnd-io/jr-plugins/internal/azure"
+)
+
+func validateCredential(config *Config) error {
+	if config.Credential.Type == "" {
+		switch {
+		case config.ConnectionString != "":
+			config.Credential.Type = ConnectionStringCredential
+		case config.SASToken != "":
+			config.Credential.Type = SASCredential
+		default:
+			config.Credential.Type = SharedKeyCredential
+		}
+	}
+
+	if config.ServiceURL == "" && config.Credential.Type != ConnectionStringCredential {
+		if config.AccountName == "" {
+			return fmt.Errorf("AccountName is mandatory")
+		}
+		config.ServiceURL = fmt.Sprintf("https://%s.blob.core.windows.net", config.AccountName)
+	}
+
+	switch config.Credential.Type {
+	case SharedKeyCredential:
+		if config.AccountName == "" {
+			return fmt.Errorf("AccountName is mandatory")
+		}
+		if config.PrimaryAccountKey == "" {
+			return fmt.Errorf("PrimaryAccountKey is mandatory")
+		}
+	case ConnectionStringCredential:
+		if config.ConnectionString == "" {
+			return fmt.Errorf("ConnectionString is mandatory")
+		}
+	case SASCredential:
+		if config.SASToken == "" {
+			return fmt.Errorf("SASToken is mandatory")
+		}
+	default:
+		return config.Credential.Validate()
+	}
+
+	return nil
+}
+
+func newClient(config Config) (*azblob.Client, error) {
+	switch config.Credential.Type {
+	case SharedKeyCredential:
+		cred, err := azblob.NewSharedKeyCredential(config.AccountName, config.PrimaryAccountKey)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClientWithSharedKeyCredential(config.ServiceURL, cred, nil)
+	case ConnectionStringCredential:
+		// e.g. UseDevelopmentStorage=true for Azurite
+		return azblob.NewClientFromConnectionString(config.ConnectionString, nil)
+	case SASCredential:
+		return azblob.NewClientWithNoCredential(
+			fmt.Sprintf("%s?%s", strings.TrimSuffix(config.ServiceURL, "/"), strings.TrimPrefix(config.SASToken, "?")),
+			nil)
+	default:
+		cred, err := azure.NewTokenCredential(config.Credential)
+		if err != nil {
+			return nil, err
+		}
+		return azblob.NewClient(config.ServiceURL, cred, nil)
+	}
+}
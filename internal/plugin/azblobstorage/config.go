@@ -22,12 +22,71 @@
 // THE SOFTWARE.
 package azblobstorage
 
+import (
+	"github.com/jrnd-io/jr-plugins/internal/azure"
+	"github.com/jrnd-io/jr-plugins/internal/mapping"
+	"github.com/jrnd-io/jr-plugins/internal/rolling"
+)
+
 type Container struct {
 	Name   string `json:"name"`
 	Create bool   `json:"create"`
 }
+
+type Mode string
+
+const (
+	BlobMode   Mode = "blob"
+	AppendMode Mode = "append"
+	BlockMode  Mode = "block"
+)
+
+type Rolling struct {
+	Prefix     string `json:"prefix"`
+	Suffix     string `json:"suffix"`
+	MaxBytes   int64  `json:"max_bytes"`
+	MaxRecords int    `json:"max_records"`
+	MaxAge     string `json:"max_age"`
+	BlockSize  int    `json:"block_size"`
+	limits     rolling.Limits
+}
+
+// Naming defines how blob names are built in blob mode, the template is rendered
+// with NameData and can reference the record key, fields of the JSON value and
+// the time, e.g. {{.Time.Format "2006/01/02"}}/{{.Value.id}}
+type Naming struct {
+	Prefix   string `json:"prefix"`
+	Template string `json:"template"`
+}
+
+type CredentialType = azure.CredentialType
+
+const (
+	SharedKeyCredential        CredentialType = "shared_key"
+	ConnectionStringCredential CredentialType = "connection_string"
+	SASCredential              CredentialType = "sas"
+	ClientSecretCredential                    = azure.ClientSecretCredential
+	ManagedIdentityCredential                 = azure.ManagedIdentityCredential
+	WorkloadIdentityCredential                = azure.WorkloadIdentityCredential
+	DefaultCredential                         = azure.DefaultCredential
+)
+
+type Credential = azure.Credential
+
 type Config struct {
-	AccountName       string    `json:"account_name"`
-	PrimaryAccountKey string    `json:"primary_account_key"`
-	Container         Container `json:"container"`
+	AccountName       string          `json:"account_name"`
+	PrimaryAccountKey string          `json:"primary_account_key"`
+	ServiceURL        string          `json:"service_url"`
+	ConnectionString  string          `json:"connection_string"`
+	SASToken          string          `json:"sas_token"`
+	Credential        Credential      `json:"credential"`
+	Container         Container       `json:"container"`
+	Mode              Mode            `json:"mode"`
+	Rolling           Rolling         `json:"rolling"`
+	Naming            Naming          `json:"name"`
+	ContentType       string          `json:"content_type"`
+	ContentEncoding   string          `json:"content_encoding"`
+	AccessTier        string          `json:"access_tier"`
+	Metadata          mapping.Mapping `json:"metadata"`
+	Tags              mapping.Mapping `json:"tags"`
 }
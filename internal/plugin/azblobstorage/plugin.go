@@ -28,9 +28,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"text/template"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
-	"github.com/google/uuid"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
 	"github.com/rs/zerolog/log"
@@ -47,6 +48,8 @@ func init() {
 type Plugin struct {
 	configuration Config
 	client        *azblob.Client
+	rolling       *rollingWriter
+	nameTemplate  *template.Template
 }
 
 func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
@@ -55,21 +58,34 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 		return err
 	}
 
-	if config.AccountName == "" {
-		return fmt.Errorf("AccountName is mandatory")
+	if err := validateCredential(&config); err != nil {
+		return err
 	}
 
-	if config.PrimaryAccountKey == "" {
-		return fmt.Errorf("PrimaryAccountKey is mandatory")
+	switch config.Mode {
+	case "":
+		config.Mode = BlobMode
+	case BlobMode:
+	case AppendMode, BlockMode:
+		if err := validateRolling(&config.Rolling); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unknown mode: %s", config.Mode)
 	}
 
-	p.configuration = config
-	cred, err := azblob.NewSharedKeyCredential(config.AccountName, config.PrimaryAccountKey)
+	if err := validateBlob(&config); err != nil {
+		return err
+	}
+
+	nameTemplate, err := ParseNameTemplate(config.Naming.Template)
 	if err != nil {
 		return err
 	}
+	p.nameTemplate = nameTemplate
 
-	client, err := azblob.NewClientWithSharedKeyCredential(fmt.Sprintf("https://%s.blob.core.windows.net", config.AccountName), cred, nil)
+	p.configuration = config
+	client, err := newClient(config)
 	if err != nil {
 		return err
 	}
@@ -80,49 +96,80 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 	}
 	if config.Container.Create {
 		_, err := client.CreateContainer(ctx, config.Container.Name, nil)
-		if err != nil {
+		if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
 			return err
 		}
 	}
 
 	p.client = client
+
+	if config.Mode != BlobMode {
+		p.rolling = newRollingWriter(
+			containerBlobs(client.ServiceClient().NewContainerClient(config.Container.Name)),
+			config.Mode,
+			config.Rolling,
+			blobOptions{
+				headers:  p.httpHeaders(),
+				metadata: p.metadata(nil),
+				tags:     config.Tags.Values(nil),
+				tier:     p.accessTier(),
+			})
+	}
 	return nil
 
 }
 
 func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.ProduceResponse, error) {
 
-	var key string
-	if len(k) == 0 || strings.ToLower(string(k)) == "null" {
-		// generate a UUID as index
-		key = uuid.New().String()
-	} else {
-		key = string(k)
+	if p.rolling != nil {
+		blob, err := p.rolling.Write(context.Background(), v)
+		if err != nil {
+			return nil, err
+		}
+		return &jrpc.ProduceResponse{
+			Bytes:   uint64(len(v)),
+			Message: blob,
+		}, nil
+	}
+
+	name, err := BlobName(p.nameTemplate, p.configuration.Naming.Prefix, k, v, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := p.metadata(headers)
+	if len(k) != 0 && strings.ToLower(string(k)) != "null" {
+		key := string(k)
+		metadata["key"] = &key
 	}
 
 	resp, err := p.client.UploadBuffer(
 		context.Background(),
 		p.configuration.Container.Name,
-		key,
+		name,
 		v,
 		&azblob.UploadBufferOptions{
-			Metadata: map[string]*string{
-				"key": &key,
-			},
+			HTTPHeaders: p.httpHeaders(),
+			Metadata:    metadata,
+			Tags:        p.configuration.Tags.Values(headers),
+			AccessTier:  p.accessTier(),
 		},
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Trace().Str("key", key).Interface("upload_resp", resp).Msg("Uploaded blob")
+	log.Trace().Str("name", name).Interface("upload_resp", resp).Msg("Uploaded blob")
 	return &jrpc.ProduceResponse{
 		Bytes:   uint64(len(v)),
-		Message: "",
+		Message: name,
 	}, nil
 
 }
 
-func (p *Plugin) Close(_ context.Context) error {
+func (p *Plugin) Close(ctx context.Context) error {
+	if p.rolling != nil {
+		return p.rolling.Close(ctx)
+	}
 	return nil
 }
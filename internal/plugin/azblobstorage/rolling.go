@@ -0,0 +1,338 @@
+//go:build azblobstorage
+// +build azblobstorage
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azblobstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/jrnd-io/jr-plugins/internal/rolling"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBlocks is the maximum number of blocks of an append or block blob
+	MaxBlocks = 50000
+
+	defaultBlockSize = 4 * 1024 * 1024
+)
+
+// appendBlob is the subset of appendblob.Client used by the rolling writer
+type appendBlob interface {
+	Create(ctx context.Context, o *appendblob.CreateOptions) (appendblob.CreateResponse, error)
+	AppendBlock(ctx context.Context, body io.ReadSeekCloser, o *appendblob.AppendBlockOptions) (appendblob.AppendBlockResponse, error)
+}
+
+// blockBlob is the subset of blockblob.Client used by the rolling writer
+type blockBlob interface {
+	StageBlock(ctx context.Context, base64BlockID string, body io.ReadSeekCloser, options *blockblob.StageBlockOptions) (blockblob.StageBlockResponse, error)
+	CommitBlockList(ctx context.Context, base64BlockIDs []string, options *blockblob.CommitBlockListOptions) (blockblob.CommitBlockListResponse, error)
+}
+
+// blobClients creates the clients of the blobs named by the rolling writer
+type blobClients struct {
+	appendBlob func(name string) appendBlob
+	blockBlob  func(name string) blockBlob
+}
+
+func containerBlobs(c *container.Client) blobClients {
+	return blobClients{
+		appendBlob: func(name string) appendBlob { return c.NewAppendBlobClient(name) },
+		blockBlob:  func(name string) blockBlob { return c.NewBlockBlobClient(name) },
+	}
+}
+
+func validateRolling(config *Rolling) error {
+	var err error
+
+	config.limits, err = rolling.ParseLimits(config.MaxBytes, config.MaxRecords, config.MaxAge)
+	if err != nil {
+		return err
+	}
+
+	if config.BlockSize == 0 {
+		config.BlockSize = defaultBlockSize
+	}
+	if config.BlockSize < 0 || config.BlockSize > blockblob.MaxStageBlockBytes {
+		return fmt.Errorf("Block size must be between 1 and %d", blockblob.MaxStageBlockBytes)
+	}
+
+	return nil
+}
+
+// blobOptions are the properties set on every blob created by the rolling writer
+type blobOptions struct {
+	headers  *blob.HTTPHeaders
+	metadata map[string]*string
+	tags     map[string]string
+	tier     *blob.AccessTier
+}
+
+// rollingWriter writes records, one per line, to the current blob.
+// In append mode every record is appended as a block, in block mode records are
+// buffered into blocks of BlockSize that are committed when the blob is rolled.
+// A blob is also rolled before it exceeds MaxBlocks.
+type rollingWriter struct {
+	mu sync.Mutex
+
+	clients blobClients
+	mode    Mode
+	config  Rolling
+	options blobOptions
+
+	seq     int
+	blob    string
+	open    bool
+	size    int64
+	records int
+	timer   *time.Timer
+
+	appendBlob appendBlob
+
+	blockBlob blockBlob
+	blockIDs  []string
+	buffer    bytes.Buffer
+
+	// commit failures of the age timer, returned by the next Write after
+	// its record is written
+	rollErr error
+}
+
+// line turns the record into a single line: JSON values are compacted and
+// other values must not span several lines, as blobs are read line by line
+func line(v []byte) ([]byte, error) {
+	record, err := rolling.JSONLine(v)
+	if err == nil {
+		return record, nil
+	}
+	if bytes.ContainsAny(v, "\r\n") {
+		return nil, fmt.Errorf("Record is neither valid JSON nor a single line")
+	}
+	return append(append(make([]byte, 0, len(v)+1), v...), '\n'), nil
+}
+
+func newRollingWriter(clients blobClients, mode Mode, config Rolling, options blobOptions) *rollingWriter {
+	return &rollingWriter{
+		clients: clients,
+		mode:    mode,
+		config:  config,
+		options: options,
+	}
+}
+
+// Write adds the record to the current blob and returns the name of the blob
+func (w *rollingWriter) Write(ctx context.Context, v []byte) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	record, err := line(v)
+	if err != nil {
+		return "", err
+	}
+
+	if !w.open {
+		if err := w.create(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	blob := w.blob
+	if err := w.add(ctx, record); err != nil {
+		return blob, err
+	}
+	w.size += int64(len(record))
+	w.records++
+
+	if w.config.limits.Reached(w.size, w.records) || w.blocks() >= MaxBlocks {
+		if err := w.finalize(ctx); err != nil {
+			return blob, err
+		}
+	}
+	return blob, w.takeRollErr()
+}
+
+// takeRollErr returns the commit failure of the age timer, if any, once the
+// current record has been written
+func (w *rollingWriter) takeRollErr() error {
+	err := w.rollErr
+	w.rollErr = nil
+	if err != nil {
+		return fmt.Errorf("Record written, but a previous rolling blob failed to commit: %w", err)
+	}
+	return nil
+}
+
+// Close commits the current blob, if any
+func (w *rollingWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.finalize(ctx)
+}
+
+func (w *rollingWriter) create(ctx context.Context) error {
+	w.seq++
+	w.blob = rolling.Name(w.config.Prefix, time.Now(), w.seq, w.config.Suffix)
+
+	switch w.mode {
+	case AppendMode:
+		w.appendBlob = w.clients.appendBlob(w.blob)
+		_, err := w.appendBlob.Create(ctx, &appendblob.CreateOptions{
+			HTTPHeaders: w.options.headers,
+			Metadata:    w.options.metadata,
+			Tags:        w.options.tags,
+		})
+		if err != nil {
+			return err
+		}
+	default:
+		w.blockBlob = w.clients.blockBlob(w.blob)
+		w.blockIDs = make([]string, 0)
+		w.buffer.Reset()
+	}
+
+	w.open = true
+	w.size = 0
+	w.records = 0
+
+	if w.config.limits.MaxAge > 0 {
+		blob := w.blob
+		w.timer = time.AfterFunc(w.config.limits.MaxAge, func() {
+			w.commitExpired(blob)
+		})
+	}
+
+	log.Debug().Str("blob", w.blob).Str("mode", string(w.mode)).Msg("Opened rolling blob")
+	return nil
+}
+
+// commitExpired commits the blob on the age timer unless a size or count
+// limit already replaced it
+func (w *rollingWriter) commitExpired(blob string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.open || w.blob != blob {
+		return
+	}
+	if err := w.finalize(context.Background()); err != nil {
+		log.Warn().Err(err).Str("blob", blob).Msg("Failed to commit rolling blob")
+		w.rollErr = err
+	}
+}
+
+// blocks returns the number of blocks of the current blob, including the
+// block being buffered in block mode
+func (w *rollingWriter) blocks() int {
+	if w.mode == AppendMode {
+		return w.records
+	}
+	if w.buffer.Len() > 0 {
+		return len(w.blockIDs) + 1
+	}
+	return len(w.blockIDs)
+}
+
+func (w *rollingWriter) add(ctx context.Context, record []byte) error {
+	if w.mode == AppendMode {
+		_, err := w.appendBlob.AppendBlock(ctx, streaming.NopCloser(bytes.NewReader(record)), nil)
+		return err
+	}
+
+	if w.buffer.Len()+len(record) > w.config.BlockSize && w.buffer.Len() > 0 {
+		if err := w.stage(ctx); err != nil {
+			return err
+		}
+	}
+	w.buffer.Write(record)
+	return nil
+}
+
+// BlockID returns the id of the n-th block, ids must have the same length within a blob
+func BlockID(n int) string {
+	id := make([]byte, 8)
+	binary.BigEndian.PutUint64(id, uint64(n))
+	return base64.StdEncoding.EncodeToString(id)
+}
+
+func (w *rollingWriter) stage(ctx context.Context) error {
+	blockID := BlockID(len(w.blockIDs))
+
+	_, err := w.blockBlob.StageBlock(ctx, blockID, streaming.NopCloser(bytes.NewReader(w.buffer.Bytes())), nil)
+	if err != nil {
+		return err
+	}
+	w.blockIDs = append(w.blockIDs, blockID)
+	w.buffer.Reset()
+	return nil
+}
+
+func (w *rollingWriter) finalize(ctx context.Context) error {
+	if !w.open {
+		return nil
+	}
+	defer w.reset()
+
+	if w.mode == BlockMode {
+		if w.buffer.Len() > 0 {
+			if err := w.stage(ctx); err != nil {
+				return err
+			}
+		}
+		_, err := w.blockBlob.CommitBlockList(ctx, w.blockIDs, &blockblob.CommitBlockListOptions{
+			HTTPHeaders: w.options.headers,
+			Metadata:    w.options.metadata,
+			Tags:        w.options.tags,
+			Tier:        w.options.tier,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debug().Str("blob", w.blob).Int("records", w.records).Msg("Committed rolling blob")
+	return nil
+}
+
+func (w *rollingWriter) reset() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.open = false
+	w.timer = nil
+	w.appendBlob = nil
+	w.blockBlob = nil
+	w.blockIDs = nil
+	w.buffer.Reset()
+}
@@ -0,0 +1,372 @@
+//go:build azblobstorage
+// +build azblobstorage
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azblobstorage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/appendblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/azblobstorage"
+)
+
+// fakeBlobs keeps the content of the committed blobs in memory
+type fakeBlobs struct {
+	mu        sync.Mutex
+	names     []string
+	committed map[string]string
+	staged    map[string]map[string]string
+	commits   int
+	commitErr error
+}
+
+func newFakeBlobs() *fakeBlobs {
+	return &fakeBlobs{
+		committed: make(map[string]string),
+		staged:    make(map[string]map[string]string),
+	}
+}
+
+func (f *fakeBlobs) appendBlob(name string) azblobstorage.AppendBlob {
+	return &fakeBlob{blobs: f, name: name}
+}
+
+func (f *fakeBlobs) blockBlob(name string) azblobstorage.BlockBlob {
+	return &fakeBlob{blobs: f, name: name}
+}
+
+// blobs returns the content of the blobs in creation order
+func (f *fakeBlobs) blobs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	blobs := make([]string, len(f.names))
+	for i, name := range f.names {
+		blobs[i] = f.committed[name]
+	}
+	return blobs
+}
+
+func (f *fakeBlobs) commitCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits
+}
+
+type fakeBlob struct {
+	blobs *fakeBlobs
+	name  string
+}
+
+func (b *fakeBlob) Create(_ context.Context, _ *appendblob.CreateOptions) (appendblob.CreateResponse, error) {
+	b.blobs.mu.Lock()
+	defer b.blobs.mu.Unlock()
+
+	b.blobs.names = append(b.blobs.names, b.name)
+	b.blobs.committed[b.name] = ""
+	return appendblob.CreateResponse{}, nil
+}
+
+func (b *fakeBlob) AppendBlock(_ context.Context, body io.ReadSeekCloser, _ *appendblob.AppendBlockOptions) (appendblob.AppendBlockResponse, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return appendblob.AppendBlockResponse{}, err
+	}
+
+	b.blobs.mu.Lock()
+	defer b.blobs.mu.Unlock()
+
+	b.blobs.committed[b.name] += string(data)
+	return appendblob.AppendBlockResponse{}, nil
+}
+
+func (b *fakeBlob) StageBlock(_ context.Context, id string, body io.ReadSeekCloser, _ *blockblob.StageBlockOptions) (blockblob.StageBlockResponse, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return blockblob.StageBlockResponse{}, err
+	}
+
+	b.blobs.mu.Lock()
+	defer b.blobs.mu.Unlock()
+
+	if b.blobs.staged[b.name] == nil {
+		b.blobs.staged[b.name] = make(map[string]string)
+	}
+	b.blobs.staged[b.name][id] = string(data)
+	return blockblob.StageBlockResponse{}, nil
+}
+
+func (b *fakeBlob) CommitBlockList(_ context.Context, ids []string, _ *blockblob.CommitBlockListOptions) (blockblob.CommitBlockListResponse, error) {
+	b.blobs.mu.Lock()
+	defer b.blobs.mu.Unlock()
+
+	b.blobs.commits++
+	if b.blobs.commitErr != nil {
+		return blockblob.CommitBlockListResponse{}, b.blobs.commitErr
+	}
+
+	var content strings.Builder
+	for _, id := range ids {
+		content.WriteString(b.blobs.staged[b.name][id])
+	}
+	b.blobs.names = append(b.blobs.names, b.name)
+	b.blobs.committed[b.name] = content.String()
+	return blockblob.CommitBlockListResponse{}, nil
+}
+
+func newRollingWriter(t *testing.T, mode azblobstorage.Mode, config azblobstorage.Rolling) (*azblobstorage.RollingWriter, *fakeBlobs) {
+	if err := azblobstorage.ValidateRolling(&config); err != nil {
+		t.Fatal(err)
+	}
+	blobs := newFakeBlobs()
+	return azblobstorage.NewRollingWriter(blobs.appendBlob, blobs.blockBlob, mode, config), blobs
+}
+
+func TestValidateRolling(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        azblobstorage.Rolling
+		wantBlockSize int
+		wantErr       bool
+	}{
+		{
+			name:          "default block size",
+			config:        azblobstorage.Rolling{MaxRecords: 10},
+			wantBlockSize: 4 * 1024 * 1024,
+		},
+		{
+			name:          "block size",
+			config:        azblobstorage.Rolling{MaxBytes: 1024, BlockSize: 512},
+			wantBlockSize: 512,
+		},
+		{
+			name:    "no limit",
+			config:  azblobstorage.Rolling{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid age",
+			config:  azblobstorage.Rolling{MaxAge: "later"},
+			wantErr: true,
+		},
+		{
+			name:    "negative block size",
+			config:  azblobstorage.Rolling{MaxRecords: 10, BlockSize: -1},
+			wantErr: true,
+		},
+		{
+			name:    "block size too large",
+			config:  azblobstorage.Rolling{MaxRecords: 10, BlockSize: blockblob.MaxStageBlockBytes + 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := azblobstorage.ValidateRolling(&tt.config)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.wantBlockSize, tt.config.BlockSize); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "compact json",
+			value: "{\n  \"id\": 1,\n  \"name\": \"a\"\n}",
+			want:  "{\"id\":1,\"name\":\"a\"}\n",
+		},
+		{
+			name:  "single line text",
+			value: "id=1 name=a",
+			want:  "id=1 name=a\n",
+		},
+		{
+			name:    "multi line text",
+			value:   "id=1\nname=a",
+			wantErr: true,
+		},
+		{
+			name:    "carriage return",
+			value:   "id=1\r",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := azblobstorage.Line([]byte(tt.value))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestRollingBlockMode(t *testing.T) {
+	// every record is 9 bytes with the newline, so a block holds two records
+	w, blobs := newRollingWriter(t, azblobstorage.BlockMode, azblobstorage.Rolling{MaxRecords: 5, BlockSize: 20})
+
+	ctx := context.Background()
+	names := make([]string, 0)
+	for i := 1; i <= 7; i++ {
+		name, err := w.Write(ctx, []byte(`{"id":`+string(rune('0'+i))+`}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	// the first blob is committed when it reaches MaxRecords
+	if diff := cmp.Diff([]string{"{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n"}, blobs.blobs()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(3, len(blobs.staged[names[0]])); diff != "" {
+		t.Errorf("blocks: mismatch (-want +got):\n%s", diff)
+	}
+
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n{\"id\":4}\n{\"id\":5}\n",
+		"{\"id\":6}\n{\"id\":7}\n",
+	}
+	if diff := cmp.Diff(want, blobs.blobs()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	if names[0] != names[4] || names[4] == names[5] {
+		t.Errorf("unexpected rolling %v", names)
+	}
+}
+
+func TestRollingAppendMode(t *testing.T) {
+	w, blobs := newRollingWriter(t, azblobstorage.AppendMode, azblobstorage.Rolling{MaxBytes: 18})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := w.Write(ctx, []byte("id=1 ok")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.Write(ctx, []byte("id=2\nbroken")); err == nil {
+		t.Errorf("expected an error for a multi line record")
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"id=1 ok\nid=1 ok\nid=1 ok\n",
+	}
+	if diff := cmp.Diff(want, blobs.blobs()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRollingMaxBlocks(t *testing.T) {
+	w, blobs := newRollingWriter(t, azblobstorage.AppendMode, azblobstorage.Rolling{MaxRecords: 2 * azblobstorage.MaxBlocks})
+
+	ctx := context.Background()
+	for i := 0; i < azblobstorage.MaxBlocks+1; i++ {
+		if _, err := w.Write(ctx, []byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got := blobs.blobs()
+	if diff := cmp.Diff(2, len(got)); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{azblobstorage.MaxBlocks, 1}, []int{strings.Count(got[0], "\n"), strings.Count(got[1], "\n")}); diff != "" {
+		t.Errorf("records: mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRollingMaxAgeError(t *testing.T) {
+	w, blobs := newRollingWriter(t, azblobstorage.BlockMode, azblobstorage.Rolling{MaxAge: "10ms"})
+	blobs.commitErr = errors.New("commit failed")
+
+	ctx := context.Background()
+	if _, err := w.Write(ctx, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for blobs.commitCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if blobs.commitCount() == 0 {
+		t.Fatalf("blob not committed on age")
+	}
+
+	blobs.mu.Lock()
+	blobs.commitErr = nil
+	blobs.mu.Unlock()
+
+	// the failed commit is reported by the next write, which still writes its record
+	if _, err := w.Write(ctx, []byte(`{"id":2}`)); err == nil {
+		t.Errorf("expected the commit error")
+	}
+	if _, err := w.Write(ctx, []byte(`{"id":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"{\"id\":2}\n{\"id\":3}\n"}, blobs.blobs()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
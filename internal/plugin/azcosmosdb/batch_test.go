@@ -0,0 +1,221 @@
+//go:build azcosmosdb
+// +build azcosmosdb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azcosmosdb_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/azcosmosdb"
+)
+
+// fakeContainer counts the executed transactional batches
+type fakeContainer struct {
+	mu       sync.Mutex
+	executed int
+	err      error
+}
+
+func (f *fakeContainer) NewTransactionalBatch(_ azcosmos.PartitionKey) azcosmos.TransactionalBatch {
+	return azcosmos.TransactionalBatch{}
+}
+
+func (f *fakeContainer) ExecuteTransactionalBatch(_ context.Context, _ azcosmos.TransactionalBatch, _ *azcosmos.TransactionalBatchOptions) (azcosmos.TransactionalBatchResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.executed++
+	if f.err != nil {
+		return azcosmos.TransactionalBatchResponse{}, f.err
+	}
+	return azcosmos.TransactionalBatchResponse{Success: true}, nil
+}
+
+func (f *fakeContainer) batches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.executed
+}
+
+func newBatchWriter(t *testing.T, batch azcosmosdb.Batch) (*azcosmosdb.BatchWriter, *fakeContainer) {
+	config := azcosmosdb.Config{PartitionKeys: []string{"/tenant"}, Batch: batch}
+	if err := azcosmosdb.ValidateWriteMode(&config); err != nil {
+		t.Fatal(err)
+	}
+	container := &fakeContainer{}
+	return azcosmosdb.NewBatchWriter(container, config), container
+}
+
+func doc(id, tenant string) (map[string]interface{}, []byte) {
+	return map[string]interface{}{"id": id, "tenant": tenant}, []byte(`{"id":"` + id + `","tenant":"` + tenant + `"}`)
+}
+
+func TestValidateWriteMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		batch   azcosmosdb.Batch
+		want    azcosmosdb.Batch
+		wantErr bool
+	}{
+		{
+			name:  "disabled",
+			batch: azcosmosdb.Batch{},
+			want:  azcosmosdb.Batch{},
+		},
+		{
+			name:  "defaults",
+			batch: azcosmosdb.Batch{Enabled: true},
+			want:  azcosmosdb.Batch{Enabled: true, Size: 100, MaxPending: 1000},
+		},
+		{
+			name:    "size too large",
+			batch:   azcosmosdb.Batch{Enabled: true, Size: 101},
+			wantErr: true,
+		},
+		{
+			name:    "max pending below the batch size",
+			batch:   azcosmosdb.Batch{Enabled: true, Size: 50, MaxPending: 10},
+			wantErr: true,
+		},
+		{
+			name:    "invalid max age",
+			batch:   azcosmosdb.Batch{Enabled: true, MaxAge: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "negative max age",
+			batch:   azcosmosdb.Batch{Enabled: true, MaxAge: "-1s"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		config := azcosmosdb.Config{Batch: tt.batch}
+		err := azcosmosdb.ValidateWriteMode(&config)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, config.Batch, cmpopts.IgnoreUnexported(azcosmosdb.Batch{})); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		batch    azcosmosdb.Batch
+		tenants  []string
+		messages []string
+		want     int
+	}{
+		{
+			name:     "batch full",
+			batch:    azcosmosdb.Batch{Enabled: true, Size: 2, MaxAge: "1h"},
+			tenants:  []string{"a", "b", "a", "b"},
+			messages: []string{"", "", "operations=2", "operations=2"},
+			want:     2,
+		},
+		{
+			name:     "max pending executes the largest batch",
+			batch:    azcosmosdb.Batch{Enabled: true, Size: 3, MaxPending: 4, MaxAge: "1h"},
+			tenants:  []string{"a", "b", "a", "c", "d"},
+			messages: []string{"", "", "", "operations=2", ""},
+			want:     1,
+		},
+	}
+
+	for _, tt := range tests {
+		w, container := newBatchWriter(t, tt.batch)
+
+		messages := make([]string, 0)
+		for i, tenant := range tt.tenants {
+			d, v := doc(string(rune('0'+i)), tenant)
+			message, err := w.Add(context.Background(), d, v)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			// only the operation count is compared, not the usage
+			operations, _, _ := strings.Cut(message, " ")
+			messages = append(messages, operations)
+		}
+
+		if diff := cmp.Diff(tt.messages, messages); diff != "" {
+			t.Errorf("%s: messages: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if diff := cmp.Diff(tt.want, container.batches()); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if err := w.Close(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestBatchWriterMaxAge(t *testing.T) {
+	w, container := newBatchWriter(t, azcosmosdb.Batch{Enabled: true, Size: 10, MaxAge: "10ms"})
+	container.err = errors.New("throttled")
+
+	d, v := doc("1", "a")
+	if _, err := w.Add(context.Background(), d, v); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for container.batches() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if diff := cmp.Diff(1, container.batches()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	container.mu.Lock()
+	container.err = nil
+	container.mu.Unlock()
+
+	// the failed batch is reported by the next add, which still buffers its record
+	d, v = doc("2", "a")
+	if _, err := w.Add(context.Background(), d, v); err == nil {
+		t.Errorf("expected the batch error")
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(2, container.batches()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
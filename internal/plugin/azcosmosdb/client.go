@@ -0,0 +1,109 @@
+//go:build azcosmosdb
+// +build azcosmosdb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azcosmosdb
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/jrnd-io/jr-plugins/internal/azure"
+	"github.com/rs/zerolog/log"
+)
+
+func newClient(config Config) (*azcosmos.Client, error) {
+	options := &azcosmos.ClientOptions{}
+	if config.InsecureSkipVerify {
+		// the emulator serves a self-signed certificate
+		options.Transport = &http.Client{
+			Transport: &http.Transport{
+				// #nosec G402
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+	}
+
+	if config.Credential.Type == KeyCredential {
+		cred, err := azcosmos.NewKeyCredential(config.PrimaryAccountKey)
+		if err != nil {
+			return nil, err
+		}
+		return azcosmos.NewClientWithKey(config.Endpoint, cred, options)
+	}
+
+	cred, err := azure.NewTokenCredential(config.Credential)
+	if err != nil {
+		return nil, err
+	}
+	return azcosmos.NewClient(config.Endpoint, cred, options)
+}
+
+func validateCredential(config *Config) error {
+	switch config.Credential.Type {
+	case "":
+		config.Credential.Type = KeyCredential
+		fallthrough
+	case KeyCredential:
+		if config.PrimaryAccountKey == "" {
+			return fmt.Errorf("PrimaryAccountKey is mandatory")
+		}
+	default:
+		return config.Credential.Validate()
+	}
+	return nil
+}
+
+// usage keeps the request units consumed by the plugin
+type usage struct {
+	mu sync.Mutex
+
+	requests      int
+	requestCharge float64
+}
+
+func (u *usage) add(resp azcosmos.Response) string {
+	u.mu.Lock()
+	u.requests++
+	u.requestCharge += float64(resp.RequestCharge)
+	u.mu.Unlock()
+
+	log.Debug().
+		Float32("request_charge", resp.RequestCharge).
+		Str("activity_id", resp.ActivityID).
+		Msg("Request completed")
+
+	return fmt.Sprintf("etag=%s request_charge=%.2f activity_id=%s", resp.ETag, resp.RequestCharge, resp.ActivityID)
+}
+
+func (u *usage) log() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	log.Info().
+		Int("requests", u.requests).
+		Float64("request_charge", u.requestCharge).
+		Msg("Total request units consumed")
+}
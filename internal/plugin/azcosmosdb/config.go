@@ -23,10 +23,54 @@
 
 package azcosmosdb
 
+import (
+	"time"
+
+	"github.com/jrnd-io/jr-plugins/internal/azure"
+)
+
+type WriteMode string
+
+const (
+	CreateMode  WriteMode = "create"
+	UpsertMode  WriteMode = "upsert"
+	ReplaceMode WriteMode = "replace"
+	PatchMode   WriteMode = "patch"
+)
+
+// Batch groups the records sharing a partition key into transactional batches.
+// MaxPending bounds the operations buffered across all partition keys and
+// MaxAge how long a batch is buffered
+type Batch struct {
+	Enabled    bool   `json:"enabled"`
+	Size       int    `json:"size"`
+	MaxPending int    `json:"max_pending"`
+	MaxAge     string `json:"max_age"`
+	maxAge     time.Duration
+}
+
+type CredentialType = azure.CredentialType
+
+const (
+	KeyCredential              CredentialType = "key"
+	ClientSecretCredential                    = azure.ClientSecretCredential
+	ManagedIdentityCredential                 = azure.ManagedIdentityCredential
+	WorkloadIdentityCredential                = azure.WorkloadIdentityCredential
+	DefaultCredential                         = azure.DefaultCredential
+)
+
+type Credential = azure.Credential
+
 type Config struct {
-	Endpoint          string `json:"endpoint"`
-	PrimaryAccountKey string `json:"primary_account_key"`
-	Database          string `json:"database"`
-	Container         string `json:"container"`
-	PartitionKey      string `json:"partition_key"`
+	Endpoint           string     `json:"endpoint"`
+	PrimaryAccountKey  string     `json:"primary_account_key"`
+	Credential         Credential `json:"credential"`
+	InsecureSkipVerify bool       `json:"insecure_skip_verify"`
+	Database           string     `json:"database"`
+	Container          string     `json:"container"`
+	PartitionKey       string     `json:"partition_key"`
+	PartitionKeys      []string   `json:"partition_keys"`
+
+	Mode  WriteMode `json:"mode"`
+	Batch Batch     `json:"batch"`
 }
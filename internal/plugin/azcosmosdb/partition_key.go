@@ -0,0 +1,102 @@
+//go:build azcosmosdb
+// +build azcosmosdb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azcosmosdb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+)
+
+// MaxPartitionKeyLevels is the maximum depth of a hierarchical partition key
+const MaxPartitionKeyLevels = 3
+
+// splitPath accepts both Cosmos DB paths (/address/city) and dotted paths (address.city)
+func splitPath(path string) []string {
+	if strings.HasPrefix(path, "/") {
+		return strings.Split(strings.TrimPrefix(path, "/"), "/")
+	}
+	return strings.Split(path, ".")
+}
+
+// ResolvePartitionKey builds the partition key from the values found at the given paths,
+// one path per level of a hierarchical partition key
+func ResolvePartitionKey(doc map[string]interface{}, paths []string) (azcosmos.PartitionKey, error) {
+	values, err := partitionKeyValues(doc, paths)
+	if err != nil {
+		return azcosmos.NewPartitionKey(), err
+	}
+	return newPartitionKey(values, paths)
+}
+
+func partitionKeyValues(doc map[string]interface{}, paths []string) ([]interface{}, error) {
+	values := make([]interface{}, len(paths))
+	for i, path := range paths {
+		value, err := lookup(doc, splitPath(path))
+		if err != nil {
+			return nil, fmt.Errorf("partition key %s: %w", path, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func newPartitionKey(values []interface{}, paths []string) (azcosmos.PartitionKey, error) {
+	pk := azcosmos.NewPartitionKey()
+
+	for i, value := range values {
+		path := paths[i]
+		switch v := value.(type) {
+		case nil:
+			pk = pk.AppendNull()
+		case string:
+			pk = pk.AppendString(v)
+		case float64:
+			pk = pk.AppendNumber(v)
+		case bool:
+			pk = pk.AppendBool(v)
+		default:
+			return pk, fmt.Errorf("partition key %s: unsupported type %T", path, value)
+		}
+	}
+
+	return pk, nil
+}
+
+func lookup(doc map[string]interface{}, path []string) (interface{}, error) {
+	value, ok := doc[path[0]]
+	if !ok {
+		return nil, fmt.Errorf("not found in value")
+	}
+	if len(path) == 1 {
+		return value, nil
+	}
+
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an object", path[0])
+	}
+	return lookup(nested, path[1:])
+}
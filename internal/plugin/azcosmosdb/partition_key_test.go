@@ -0,0 +1,82 @@
+//go:build azcosmosdb
+// +build azcosmosdb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azcosmosdb_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/azcosmosdb"
+)
+
+func TestResolvePartitionKey(t *testing.T) {
+	value := `{"id":"1","tenant":"jr","count":3,"active":true,"deleted":null,"address":{"city":"Rome"}}`
+
+	testCases := []struct {
+		name    string
+		paths   []string
+		want    azcosmos.PartitionKey
+		wantErr bool
+	}{
+		{name: "string", paths: []string{"tenant"}, want: azcosmos.NewPartitionKeyString("jr")},
+		{name: "number", paths: []string{"count"}, want: azcosmos.NewPartitionKeyNumber(3)},
+		{name: "bool", paths: []string{"active"}, want: azcosmos.NewPartitionKeyBool(true)},
+		{name: "null", paths: []string{"deleted"}, want: azcosmos.NewPartitionKey().AppendNull()},
+		{name: "nested_path", paths: []string{"/address/city"}, want: azcosmos.NewPartitionKeyString("Rome")},
+		{name: "nested_dotted", paths: []string{"address.city"}, want: azcosmos.NewPartitionKeyString("Rome")},
+		{
+			name:  "hierarchical",
+			paths: []string{"/tenant", "/address/city", "/count"},
+			want:  azcosmos.NewPartitionKeyString("jr").AppendString("Rome").AppendNumber(3),
+		},
+		{name: "missing", paths: []string{"missing"}, wantErr: true},
+		{name: "object", paths: []string{"address"}, wantErr: true},
+		{name: "not_an_object", paths: []string{"tenant.name"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc map[string]interface{}
+			if err := json.Unmarshal([]byte(value), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			pk, err := azcosmosdb.ResolvePartitionKey(doc, tc.paths)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tc.want, pk) {
+				t.Errorf("%s: mismatch want %+v got %+v", tc.name, tc.want, pk)
+			}
+		})
+	}
+}
@@ -45,6 +45,9 @@ func init() {
 type Plugin struct {
 	configuration Config
 	client        *azcosmos.Client
+	container     *azcosmos.ContainerClient
+	batch         *batchWriter
+	usage         usage
 }
 
 func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
@@ -57,26 +60,44 @@ func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
 		return fmt.Errorf("Endpoint is mandatory")
 	}
 
-	if config.PrimaryAccountKey == "" {
-		return fmt.Errorf("PrimaryAccountKey is mandatory")
+	if err := validateCredential(&config); err != nil {
+		return err
 	}
 
-	if config.PartitionKey == "" {
+	if config.PartitionKey == "" && len(config.PartitionKeys) == 0 {
 		return fmt.Errorf("PartitionKey is mandatory")
 	}
+	if config.PartitionKey != "" && len(config.PartitionKeys) > 0 {
+		return fmt.Errorf("PartitionKey and PartitionKeys are mutually exclusive")
+	}
+	if config.PartitionKey != "" {
+		config.PartitionKeys = []string{config.PartitionKey}
+	}
+	if len(config.PartitionKeys) > MaxPartitionKeyLevels {
+		return fmt.Errorf("At most %d partition key levels are supported", MaxPartitionKeyLevels)
+	}
+
+	if err := validateWriteMode(&config); err != nil {
+		return err
+	}
 
-	cred, err := azcosmos.NewKeyCredential(config.PrimaryAccountKey)
+	client, err := newClient(config)
 	if err != nil {
 		return err
 	}
 
-	client, err := azcosmos.NewClientWithKey(config.Endpoint, cred, nil)
+	container, err := client.NewContainer(config.Database, config.Container)
 	if err != nil {
 		return err
 	}
 
 	p.configuration = config
 	p.client = client
+	p.container = container
+
+	if config.Batch.Enabled {
+		p.batch = newBatchWriter(container, config, &p.usage)
+	}
 	return nil
 
 }
@@ -89,34 +110,40 @@ func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.P
 		return nil, err
 	}
 
-	// getting partition key value
-	pkValue := jsonMap[p.configuration.PartitionKey]
-	if pkValue == nil {
-		return nil, fmt.Errorf("Partition key not found in value")
+	if p.batch != nil {
+		message, err := p.batch.Add(context.Background(), jsonMap, v)
+		if err != nil {
+			return nil, err
+		}
+		return &jrpc.ProduceResponse{
+			Bytes:   uint64(len(v)),
+			Message: message,
+		}, nil
 	}
-	log.Debug().Str("pkValue", pkValue.(string)).Msg("Partition key value")
 
-	container, err := p.client.NewContainer(p.configuration.Database, p.configuration.Container)
+	// getting partition key value
+	pk, err := ResolvePartitionKey(jsonMap, p.configuration.PartitionKeys)
 	if err != nil {
 		return nil, err
-		log.Fatal().Err(err).Msg("Failed to create container")
 	}
+	log.Debug().Strs("paths", p.configuration.PartitionKeys).Msg("Partition key resolved")
 
-	pk := azcosmos.NewPartitionKeyString(pkValue.(string))
-	resp, err := container.CreateItem(context.Background(), pk, v, nil)
+	resp, err := p.write(context.Background(), pk, jsonMap, v)
 	if err != nil {
 		return nil, err
-		log.Fatal().Err(err).Msg("Failed to create item")
 	}
 
-	log.Debug().Interface("resp", resp).Msg("Item created")
 	return &jrpc.ProduceResponse{
 		Bytes:   uint64(len(v)),
-		Message: string(resp.ETag),
+		Message: p.usage.add(resp.Response),
 	}, nil
 
 }
 
-func (p *Plugin) Close(_ context.Context) error {
+func (p *Plugin) Close(ctx context.Context) error {
+	defer p.usage.log()
+	if p.batch != nil {
+		return p.batch.Close(ctx)
+	}
 	return nil
 }
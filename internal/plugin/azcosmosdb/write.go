@@ -0,0 +1,370 @@
+//go:build azcosmosdb
+// +build azcosmosdb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package azcosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/data/azcosmos"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MaxBatchSize is the maximum number of operations in a transactional batch
+	MaxBatchSize = 100
+	// MaxPatchOperations is the maximum number of operations in a patch
+	MaxPatchOperations = 10
+
+	DefaultBatchMaxPending = 1000
+	DefaultBatchMaxAge     = 5 * time.Second
+)
+
+func validateWriteMode(config *Config) error {
+	switch config.Mode {
+	case "":
+		config.Mode = CreateMode
+	case CreateMode, UpsertMode, ReplaceMode, PatchMode:
+	default:
+		return fmt.Errorf("Unknown mode: %s", config.Mode)
+	}
+
+	if config.Batch.Enabled {
+		if config.Batch.Size == 0 {
+			config.Batch.Size = MaxBatchSize
+		}
+		if config.Batch.Size < 0 || config.Batch.Size > MaxBatchSize {
+			return fmt.Errorf("Batch size must be between 1 and %d", MaxBatchSize)
+		}
+		if config.Batch.MaxPending == 0 {
+			config.Batch.MaxPending = DefaultBatchMaxPending
+		}
+		if config.Batch.MaxPending < config.Batch.Size {
+			return fmt.Errorf("Max pending must be at least the batch size")
+		}
+		maxAge := DefaultBatchMaxAge
+		if config.Batch.MaxAge != "" {
+			var err error
+			maxAge, err = time.ParseDuration(config.Batch.MaxAge)
+			if err != nil {
+				return fmt.Errorf("Invalid batch max age: %w", err)
+			}
+		}
+		if maxAge <= 0 {
+			return fmt.Errorf("Batch max age must be positive")
+		}
+		config.Batch.maxAge = maxAge
+	}
+	return nil
+}
+
+func itemID(doc map[string]interface{}) (string, error) {
+	id, ok := doc["id"].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("id not found in value")
+	}
+	return id, nil
+}
+
+// BuildPatch sets every top level property but the id and the ones holding
+// the partition key, which cannot be patched
+func BuildPatch(doc map[string]interface{}, partitionKeys []string) (azcosmos.PatchOperations, error) {
+	patch := azcosmos.PatchOperations{}
+
+	excluded := map[string]bool{"id": true}
+	for _, path := range partitionKeys {
+		excluded[splitPath(path)[0]] = true
+	}
+
+	properties := make([]string, 0, len(doc))
+	for k := range doc {
+		if !excluded[k] {
+			properties = append(properties, k)
+		}
+	}
+	if len(properties) > MaxPatchOperations {
+		return patch, fmt.Errorf("%d properties exceed the limit of %d patch operations", len(properties), MaxPatchOperations)
+	}
+	sort.Strings(properties)
+
+	for _, k := range properties {
+		patch.AppendSet("/"+k, doc[k])
+	}
+	return patch, nil
+}
+
+func (p *Plugin) write(ctx context.Context, pk azcosmos.PartitionKey, doc map[string]interface{}, v []byte) (azcosmos.ItemResponse, error) {
+	switch p.configuration.Mode {
+	case UpsertMode:
+		return p.container.UpsertItem(ctx, pk, v, nil)
+	case ReplaceMode:
+		id, err := itemID(doc)
+		if err != nil {
+			return azcosmos.ItemResponse{}, err
+		}
+		return p.container.ReplaceItem(ctx, pk, id, v, nil)
+	case PatchMode:
+		id, err := itemID(doc)
+		if err != nil {
+			return azcosmos.ItemResponse{}, err
+		}
+		patch, err := BuildPatch(doc, p.configuration.PartitionKeys)
+		if err != nil {
+			return azcosmos.ItemResponse{}, err
+		}
+		return p.container.PatchItem(ctx, pk, id, patch, nil)
+	default:
+		return p.container.CreateItem(ctx, pk, v, nil)
+	}
+}
+
+// batchContainer is the subset of azcosmos.ContainerClient used by the batch writer
+type batchContainer interface {
+	NewTransactionalBatch(partitionKey azcosmos.PartitionKey) azcosmos.TransactionalBatch
+	ExecuteTransactionalBatch(ctx context.Context, b azcosmos.TransactionalBatch, o *azcosmos.TransactionalBatchOptions) (azcosmos.TransactionalBatchResponse, error)
+}
+
+type pendingBatch struct {
+	batch   azcosmos.TransactionalBatch
+	count   int
+	created time.Time
+}
+
+// batchWriter groups the records sharing a partition key into transactional
+// batches. A batch is executed when it reaches the batch size or MaxAge, and
+// the largest batch is executed when the operations buffered across all
+// partition keys reach MaxPending
+type batchWriter struct {
+	mu sync.Mutex
+
+	container     batchContainer
+	mode          WriteMode
+	size          int
+	maxPending    int
+	maxAge        time.Duration
+	partitionKeys []string
+	pending       map[string]*pendingBatch
+	operations    int
+	usage         *usage
+
+	ticker *time.Ticker
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	// failures of the age flush, returned by the next Add or by Close
+	errs []error
+}
+
+func newBatchWriter(container batchContainer, config Config, usage *usage) *batchWriter {
+	b := &batchWriter{
+		usage:         usage,
+		container:     container,
+		mode:          config.Mode,
+		size:          config.Batch.Size,
+		maxPending:    config.Batch.MaxPending,
+		maxAge:        config.Batch.maxAge,
+		partitionKeys: config.PartitionKeys,
+		pending:       make(map[string]*pendingBatch),
+		done:          make(chan struct{}),
+	}
+
+	b.ticker = time.NewTicker(max(b.maxAge/2, time.Millisecond))
+	b.wg.Add(1)
+	go b.executeExpired()
+
+	return b
+}
+
+// Add appends the record to the batch of its partition key and executes
+// the batch once it is full, or the largest batch once MaxPending operations
+// are buffered, returning its outcome
+func (b *batchWriter) Add(ctx context.Context, doc map[string]interface{}, v []byte) (string, error) {
+	values, err := partitionKeyValues(doc, b.partitionKeys)
+	if err != nil {
+		return "", err
+	}
+	pk, err := newPartitionKey(values, b.partitionKeys)
+	if err != nil {
+		return "", err
+	}
+	// the JSON encoding of the values identifies the partition key
+	group, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, ok := b.pending[string(group)]
+	if !ok {
+		pending = &pendingBatch{batch: b.container.NewTransactionalBatch(pk), created: time.Now()}
+		b.pending[string(group)] = pending
+	}
+
+	if err := b.append(&pending.batch, doc, v); err != nil {
+		if pending.count == 0 {
+			delete(b.pending, string(group))
+		}
+		return "", err
+	}
+	pending.count++
+	b.operations++
+
+	var message string
+	switch {
+	case pending.count >= b.size:
+		message, err = b.execute(ctx, string(group))
+	case b.operations >= b.maxPending:
+		message, err = b.execute(ctx, b.largest())
+	}
+	if err != nil {
+		return message, err
+	}
+	return message, b.takeErrors()
+}
+
+func (b *batchWriter) append(batch *azcosmos.TransactionalBatch, doc map[string]interface{}, v []byte) error {
+	switch b.mode {
+	case UpsertMode:
+		batch.UpsertItem(v, nil)
+	case ReplaceMode:
+		id, err := itemID(doc)
+		if err != nil {
+			return err
+		}
+		batch.ReplaceItem(id, v, nil)
+	case PatchMode:
+		id, err := itemID(doc)
+		if err != nil {
+			return err
+		}
+		patch, err := BuildPatch(doc, b.partitionKeys)
+		if err != nil {
+			return err
+		}
+		batch.PatchItem(id, patch, nil)
+	default:
+		batch.CreateItem(v, nil)
+	}
+	return nil
+}
+
+// Close stops the age flush and executes every pending batch
+func (b *batchWriter) Close(ctx context.Context) error {
+	close(b.done)
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	errs := b.errs
+	b.errs = nil
+	for group := range b.pending {
+		if _, err := b.execute(ctx, group); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d batches failed, first error: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+// executeExpired executes the batches buffered for longer than MaxAge
+func (b *batchWriter) executeExpired() {
+	defer b.wg.Done()
+	defer b.ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case now := <-b.ticker.C:
+			b.mu.Lock()
+			for group, pending := range b.pending {
+				if now.Sub(pending.created) < b.maxAge {
+					continue
+				}
+				if _, err := b.execute(context.Background(), group); err != nil {
+					log.Warn().Err(err).Msg("Failed to execute expired transactional batch")
+					b.errs = append(b.errs, err)
+				}
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// largest returns the partition key with the most buffered operations
+func (b *batchWriter) largest() string {
+	var group string
+	count := 0
+	for g, pending := range b.pending {
+		if pending.count > count {
+			group, count = g, pending.count
+		}
+	}
+	return group
+}
+
+func (b *batchWriter) takeErrors() error {
+	if len(b.errs) == 0 {
+		return nil
+	}
+	err := fmt.Errorf("Record added, but %d previous batches failed, first error: %w", len(b.errs), b.errs[0])
+	b.errs = nil
+	return err
+}
+
+// execute removes the batch of the partition key and executes it
+func (b *batchWriter) execute(ctx context.Context, group string) (string, error) {
+	pending := b.pending[group]
+	delete(b.pending, group)
+	b.operations -= pending.count
+
+	resp, err := b.container.ExecuteTransactionalBatch(ctx, pending.batch, nil)
+	if err != nil {
+		return "", err
+	}
+	message := fmt.Sprintf("operations=%d %s", pending.count, b.usage.add(resp.Response))
+
+	if !resp.Success {
+		// the failing operation is the one not reported as a failed dependency
+		for i, result := range resp.OperationResults {
+			if result.StatusCode >= 400 && result.StatusCode != 424 {
+				return message, fmt.Errorf("transactional batch of %d operations rolled back: operation %d failed with status %d",
+					pending.count, i, result.StatusCode)
+			}
+		}
+		return message, fmt.Errorf("transactional batch of %d operations rolled back", pending.count)
+	}
+
+	log.Debug().Int("operations", pending.count).Msg("Transactional batch committed")
+	return message, nil
+}
@@ -0,0 +1,146 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMinBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+func parseDuration(name string, value string, def time.Duration) (time.Duration, error) {
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// buildCluster configures the cluster from the configuration, the session
+// is created by the caller
+func buildCluster(config Config) (*gocql.ClusterConfig, error) {
+	cluster := gocql.NewCluster(config.Hosts...)
+
+	if config.Username != "" || config.Password != "" {
+		if config.Username == "" || config.Password == "" {
+			return nil, fmt.Errorf("Username and password are both required when authentication is enabled")
+		}
+		cluster.Authenticator = gocql.PasswordAuthenticator{
+			Username: config.Username,
+			Password: config.Password,
+		}
+	}
+
+	if config.Port > 0 {
+		cluster.Port = config.Port
+	}
+	if config.ProtocolVersion > 0 {
+		cluster.ProtoVersion = config.ProtocolVersion
+	}
+
+	if config.LocalDC != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(config.LocalDC))
+	} else {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy())
+	}
+
+	timeout, err := parseDuration("timeout", config.Timeout, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	cluster.Timeout = timeout
+
+	connectTimeout, err := parseDuration("connect timeout", config.ConnectTimeout, timeout)
+	if err != nil {
+		return nil, err
+	}
+	cluster.ConnectTimeout = connectTimeout
+
+	// certificate files imply TLS, they would be silently ignored otherwise
+	if config.TLS.Enabled || config.TLS.CertFile != "" || config.TLS.KeyFile != "" || config.TLS.RootCAFile != "" {
+		cluster.SslOpts = &gocql.SslOptions{
+			// #nosec G402
+			Config: &tls.Config{
+				InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+				MinVersion:         tls.VersionTLS12,
+			},
+			EnableHostVerification: !config.TLS.InsecureSkipVerify,
+			CertPath:               config.TLS.CertFile,
+			KeyPath:                config.TLS.KeyFile,
+			CaPath:                 config.TLS.RootCAFile,
+		}
+	}
+
+	if config.Retry.NumRetries > 0 {
+		minBackoff, err := parseDuration("min backoff", config.Retry.MinBackoff, DefaultMinBackoff)
+		if err != nil {
+			return nil, err
+		}
+		maxBackoff, err := parseDuration("max backoff", config.Retry.MaxBackoff, DefaultMaxBackoff)
+		if err != nil {
+			return nil, err
+		}
+		cluster.RetryPolicy = &gocql.ExponentialBackoffRetryPolicy{
+			NumRetries: config.Retry.NumRetries,
+			Min:        minBackoff,
+			Max:        maxBackoff,
+		}
+	}
+
+	// inserts are idempotent, which is required by speculative executions
+	cluster.DefaultIdempotence = config.Speculative.Attempts > 0
+
+	return cluster, nil
+}
+
+// buildSpeculativePolicy returns the speculative execution policy applied to every query
+func buildSpeculativePolicy(config SpeculativeExecution) (gocql.SpeculativeExecutionPolicy, error) {
+	if config.Attempts <= 0 {
+		return &gocql.NonSpeculativeExecution{}, nil
+	}
+
+	delay, err := parseDuration("speculative execution delay", config.Delay, 0)
+	if err != nil {
+		return nil, err
+	}
+	if delay <= 0 {
+		return nil, fmt.Errorf("Speculative execution delay is mandatory")
+	}
+
+	return &gocql.SimpleSpeculativeExecution{
+		NumAttempts:  config.Attempts,
+		TimeoutDelay: delay,
+	}, nil
+}
@@ -0,0 +1,209 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/cassandra"
+)
+
+func TestBuildCluster(t *testing.T) {
+	type cluster struct {
+		Port               int
+		Timeout            time.Duration
+		ConnectTimeout     time.Duration
+		TLS                bool
+		InsecureSkipVerify bool
+		CaPath             string
+		Authenticator      gocql.Authenticator
+		RetryPolicy        gocql.RetryPolicy
+		DefaultIdempotence bool
+	}
+
+	tests := []struct {
+		name    string
+		config  cassandra.Config
+		want    cluster
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: cassandra.Config{Hosts: []string{"localhost"}},
+			want: cluster{
+				Port:           9042,
+				Timeout:        cassandra.DefaultTimeout,
+				ConnectTimeout: cassandra.DefaultTimeout,
+			},
+		},
+		{
+			name: "timeouts auth and retry",
+			config: cassandra.Config{
+				Hosts:          []string{"localhost"},
+				Port:           9142,
+				Timeout:        "2s",
+				ConnectTimeout: "1s",
+				Username:       "user",
+				Password:       "pass",
+				Retry:          cassandra.Retry{NumRetries: 3, MinBackoff: "50ms"},
+				Speculative:    cassandra.SpeculativeExecution{Attempts: 2, Delay: "100ms"},
+			},
+			want: cluster{
+				Port:           9142,
+				Timeout:        2 * time.Second,
+				ConnectTimeout: time.Second,
+				Authenticator:  gocql.PasswordAuthenticator{Username: "user", Password: "pass"},
+				RetryPolicy: &gocql.ExponentialBackoffRetryPolicy{
+					NumRetries: 3,
+					Min:        50 * time.Millisecond,
+					Max:        cassandra.DefaultMaxBackoff,
+				},
+				DefaultIdempotence: true,
+			},
+		},
+		{
+			name: "tls enabled",
+			config: cassandra.Config{
+				Hosts: []string{"localhost"},
+				TLS:   cassandra.TLS{Enabled: true, InsecureSkipVerify: true},
+			},
+			want: cluster{
+				Port:               9042,
+				Timeout:            cassandra.DefaultTimeout,
+				ConnectTimeout:     cassandra.DefaultTimeout,
+				TLS:                true,
+				InsecureSkipVerify: true,
+			},
+		},
+		{
+			name: "tls implied by root ca file",
+			config: cassandra.Config{
+				Hosts: []string{"localhost"},
+				TLS:   cassandra.TLS{RootCAFile: "ca.pem"},
+			},
+			want: cluster{
+				Port:           9042,
+				Timeout:        cassandra.DefaultTimeout,
+				ConnectTimeout: cassandra.DefaultTimeout,
+				TLS:            true,
+				CaPath:         "ca.pem",
+			},
+		},
+		{
+			name:    "invalid timeout",
+			config:  cassandra.Config{Hosts: []string{"localhost"}, Timeout: "10"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid connect timeout",
+			config:  cassandra.Config{Hosts: []string{"localhost"}, ConnectTimeout: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "username without password",
+			config:  cassandra.Config{Hosts: []string{"localhost"}, Username: "user"},
+			wantErr: true,
+		},
+		{
+			name: "invalid backoff",
+			config: cassandra.Config{
+				Hosts: []string{"localhost"},
+				Retry: cassandra.Retry{NumRetries: 1, MaxBackoff: "forever"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := cassandra.BuildCluster(tt.config)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		gotCluster := cluster{
+			Port:               got.Port,
+			Timeout:            got.Timeout,
+			ConnectTimeout:     got.ConnectTimeout,
+			TLS:                got.SslOpts != nil,
+			Authenticator:      got.Authenticator,
+			RetryPolicy:        got.RetryPolicy,
+			DefaultIdempotence: got.DefaultIdempotence,
+		}
+		if got.SslOpts != nil {
+			gotCluster.InsecureSkipVerify = got.SslOpts.Config.InsecureSkipVerify
+			gotCluster.CaPath = got.SslOpts.CaPath
+		}
+		if diff := cmp.Diff(tt.want, gotCluster); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBuildSpeculativePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  cassandra.SpeculativeExecution
+		want    gocql.SpeculativeExecutionPolicy
+		wantErr bool
+	}{
+		{
+			name:   "disabled",
+			config: cassandra.SpeculativeExecution{},
+			want:   &gocql.NonSpeculativeExecution{},
+		},
+		{
+			name:   "attempts and delay",
+			config: cassandra.SpeculativeExecution{Attempts: 2, Delay: "200ms"},
+			want:   &gocql.SimpleSpeculativeExecution{NumAttempts: 2, TimeoutDelay: 200 * time.Millisecond},
+		},
+		{
+			name:    "missing delay",
+			config:  cassandra.SpeculativeExecution{Attempts: 2},
+			wantErr: true,
+		},
+		{
+			name:    "invalid delay",
+			config:  cassandra.SpeculativeExecution{Attempts: 2, Delay: "later"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := cassandra.BuildSpeculativePolicy(tt.config)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
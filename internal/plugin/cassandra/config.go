@@ -23,12 +23,116 @@
 
 package cassandra
 
+import "time"
+
+// Missing defines how fields missing from a record are written
+type Missing string
+
+const (
+	// MissingNull writes missing fields as null, creating tombstones
+	MissingNull Missing = "null"
+	// MissingUnset leaves missing fields untouched
+	MissingUnset Missing = "unset"
+)
+
+// Column maps a field of the JSON record, as a dotted path, to a table column
+type Column struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// TTL sets the USING TTL of the insert from a fixed duration, a record field
+// holding the TTL in seconds or both, the duration being used when the field is missing
+type TTL struct {
+	Duration string `json:"duration"`
+	Field    string `json:"field"`
+	seconds  int64
+}
+
+// Timestamp sets the USING TIMESTAMP of the insert from a record field holding
+// microseconds since epoch or an RFC 3339 date
+type Timestamp struct {
+	Field string `json:"field"`
+}
+
+// WriteMode defines how records are written
+type WriteMode string
+
+const (
+	// SyncMode executes an insert per record and waits for it
+	SyncMode WriteMode = "sync"
+	// BatchMode groups records into unlogged batches per partition key
+	BatchMode WriteMode = "batch"
+	// AsyncMode executes inserts concurrently, bounded by the in-flight limit
+	AsyncMode WriteMode = "async"
+)
+
+// Batch configures the batch mode, PartitionKey holds the paths of the
+// partition key columns in the JSON record. MaxPending bounds the records
+// buffered across all partitions and MaxAge how long a partition is buffered
+type Batch struct {
+	Size         int      `json:"size"`
+	PartitionKey []string `json:"partition_key"`
+	MaxPending   int      `json:"max_pending"`
+	MaxAge       string   `json:"max_age"`
+	maxAge       time.Duration
+}
+
+// Async configures the async mode
+type Async struct {
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+type TLS struct {
+	Enabled            bool   `json:"enabled"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	RootCAFile         string `json:"root_ca_file"`
+}
+
+// Retry configures an exponential backoff retry policy
+type Retry struct {
+	NumRetries int    `json:"num_retries"`
+	MinBackoff string `json:"min_backoff"`
+	MaxBackoff string `json:"max_backoff"`
+}
+
+// SpeculativeExecution configures additional executions of a query sent to
+// other hosts when no response is received within the delay
+type SpeculativeExecution struct {
+	Attempts int    `json:"attempts"`
+	Delay    string `json:"delay"`
+}
+
+// Schema holds CQL statements executed at Init, inline or from a .cql file,
+// usually CREATE KEYSPACE/TABLE IF NOT EXISTS to bootstrap fresh clusters
+type Schema struct {
+	Statements []string `json:"statements"`
+	File       string   `json:"file"`
+}
+
 type Config struct {
-	Hosts            []string `json:"hosts"`
-	Timeout          string   `json:"timeout"`
-	Keyspace         string   `json:"keyspace"`
-	Table            string   `json:"table"`
-	ConsistencyLevel string   `json:"consistencyLevel"`
-	Username         string   `json:"username"`
-	Password         string   `json:"password"`
+	Hosts            []string             `json:"hosts"`
+	Port             int                  `json:"port"`
+	ProtocolVersion  int                  `json:"protocol_version"`
+	LocalDC          string               `json:"local_dc"`
+	Timeout          string               `json:"timeout"`
+	ConnectTimeout   string               `json:"connect_timeout"`
+	Keyspace         string               `json:"keyspace"`
+	Table            string               `json:"table"`
+	ConsistencyLevel string               `json:"consistencyLevel"`
+	Username         string               `json:"username"`
+	Password         string               `json:"password"`
+	TLS              TLS                  `json:"tls"`
+	Retry            Retry                `json:"retry"`
+	Speculative      SpeculativeExecution `json:"speculative_execution"`
+	Columns          []Column             `json:"columns"`
+	Missing          Missing              `json:"missing"`
+	TTL              TTL                  `json:"ttl"`
+	Timestamp        Timestamp            `json:"timestamp"`
+	Mode             WriteMode            `json:"mode"`
+	Batch            Batch                `json:"batch"`
+	Async            Async                `json:"async"`
+	Schema           Schema               `json:"schema"`
 }
@@ -27,12 +27,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/gocql/gocql"
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
-	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -48,10 +46,13 @@ type Plugin struct {
 
 	session          *gocql.Session
 	consistencyLevel gocql.Consistency
-	timeout          time.Duration
+	spec             gocql.SpeculativeExecutionPolicy
+	statement        string
+	batch            *batchWriter
+	async            *asyncWriter
 }
 
-func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
+func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 	config := Config{}
 	if err := json.Unmarshal(cfgBytes, &config); err != nil {
 		return err
@@ -61,12 +62,20 @@ func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
 		return fmt.Errorf("Keyspace is required")
 	}
 
+	if config.Table == "" {
+		return fmt.Errorf("Table is required")
+	}
+
 	if len(config.Hosts) == 0 {
 		return fmt.Errorf("Hosts are required")
 	}
 
-	if config.Username == "" || config.Password == "" {
-		return fmt.Errorf("Username and password are both required")
+	if err := validateStatement(&config); err != nil {
+		return err
+	}
+
+	if err := validateMode(&config); err != nil {
+		return err
 	}
 
 	if config.ConsistencyLevel == "" {
@@ -82,26 +91,38 @@ func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
 		config.Timeout = "10s"
 	}
 
-	timeout, err := time.ParseDuration(config.Timeout)
+	cluster, err := buildCluster(config)
 	if err != nil {
-		log.Warn().Err(err).Msg("Failed to parse timeout, setting default to 10s")
-		timeout = time.Second * 10
+		return err
 	}
 
-	cluster := gocql.NewCluster(config.Hosts...)
-	cluster.Authenticator = gocql.PasswordAuthenticator{
-		Username: config.Username,
-		Password: config.Password,
+	spec, err := buildSpeculativePolicy(config.Speculative)
+	if err != nil {
+		return err
 	}
+
 	session, err := cluster.CreateSession()
 	if err != nil {
 		return err
 	}
 
+	if err := createSchema(ctx, session, config.Schema); err != nil {
+		session.Close()
+		return err
+	}
+
 	p.configuration = config
-	p.timeout = timeout
+	p.spec = spec
 	p.session = session
 	p.consistencyLevel = consistencyLevel
+	p.statement = BuildInsert(config)
+
+	switch config.Mode {
+	case BatchMode:
+		p.batch = newBatchWriter(sessionBatch(session, consistencyLevel, spec, p.statement), config.Batch)
+	case AsyncMode:
+		p.async = newAsyncWriter(sessionInsert(session, consistencyLevel, spec, p.statement), config.Async)
+	}
 
 	return nil
 
@@ -109,20 +130,51 @@ func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
 
 func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.ProduceResponse, error) {
 
-	stmt := fmt.Sprintf("INSERT INTO %s.%s JSON ?",
-		p.configuration.Keyspace,
-		p.configuration.Table)
-	if err := p.session.Query(stmt, string(v)).
-		Consistency(p.consistencyLevel).Exec(); err != nil {
+	values, err := BindValues(p.configuration, v)
+	if err != nil {
 		return nil, err
 	}
+
+	var message string
+
+	switch {
+	case p.batch != nil:
+		key, err := PartitionKeyOf(p.configuration.Batch.PartitionKey, v)
+		if err != nil {
+			return nil, err
+		}
+		message, err = p.batch.Add(context.Background(), key, values)
+		if err != nil {
+			return nil, err
+		}
+	case p.async != nil:
+		if err := p.async.Write(context.Background(), values); err != nil {
+			return nil, err
+		}
+	default:
+		if err := p.session.Query(p.statement, values...).
+			Consistency(p.consistencyLevel).
+			SetSpeculativeExecutionPolicy(p.spec).
+			Exec(); err != nil {
+			return nil, err
+		}
+	}
+
 	return &jrpc.ProduceResponse{
 		Bytes:   uint64(len(v)),
-		Message: "",
+		Message: message,
 	}, nil
 }
 
-func (p *Plugin) Close(_ context.Context) error {
+func (p *Plugin) Close(ctx context.Context) error {
+	var err error
+	switch {
+	case p.batch != nil:
+		err = p.batch.Close(ctx)
+	case p.async != nil:
+		err = p.async.Close()
+	}
+
 	p.session.Close()
-	return nil
+	return err
 }
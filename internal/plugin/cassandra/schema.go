@@ -0,0 +1,128 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/rs/zerolog/log"
+)
+
+// SplitStatements splits a CQL script into statements, skipping comments and
+// ignoring semicolons within quoted strings and identifiers
+func SplitStatements(cql string) []string {
+	statements := make([]string, 0)
+
+	var current strings.Builder
+	var quote rune
+
+	add := func() {
+		stmt := strings.TrimSpace(current.String())
+		if stmt != "" {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(cql)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case quote != 0:
+			current.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			current.WriteRune(r)
+		case r == '-' && next == '-', r == '/' && next == '/':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case r == '/' && next == '*':
+			i += 2
+			for i < len(runes) && (runes[i] != '*' || i+1 >= len(runes) || runes[i+1] != '/') {
+				i++
+			}
+			i++
+			current.WriteRune(' ')
+		case r == ';':
+			add()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	add()
+
+	return statements
+}
+
+func schemaStatements(config Schema) ([]string, error) {
+	statements := make([]string, 0, len(config.Statements))
+	for _, stmt := range config.Statements {
+		statements = append(statements, SplitStatements(stmt)...)
+	}
+
+	if config.File != "" {
+		cql, err := os.ReadFile(config.File)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, SplitStatements(string(cql))...)
+	}
+
+	return statements, nil
+}
+
+// createSchema executes the schema statements and waits for the cluster to
+// agree on the resulting schema
+func createSchema(ctx context.Context, session *gocql.Session, config Schema) error {
+	statements, err := schemaStatements(config)
+	if err != nil {
+		return err
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	for _, stmt := range statements {
+		log.Debug().Str("statement", stmt).Msg("Executing schema statement")
+		if err := session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+			return fmt.Errorf("schema statement %q failed: %w", stmt, err)
+		}
+	}
+
+	return session.AwaitSchemaAgreement(ctx)
+}
@@ -0,0 +1,60 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/cassandra"
+)
+
+func TestSplitStatements(t *testing.T) {
+	cql := `
+-- keyspace
+CREATE KEYSPACE IF NOT EXISTS jr
+  WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1};
+
+/* table; with a comment */
+CREATE TABLE IF NOT EXISTS jr.users (
+  id text PRIMARY KEY, // the key
+  note text
+);
+INSERT INTO jr.users (id, note) VALUES ('1', 'it''s; fine');
+`
+
+	want := []string{
+		"CREATE KEYSPACE IF NOT EXISTS jr\n  WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}",
+		"CREATE TABLE IF NOT EXISTS jr.users (\n  id text PRIMARY KEY, \n  note text\n)",
+		"INSERT INTO jr.users (id, note) VALUES ('1', 'it''s; fine')",
+	}
+
+	if diff := cmp.Diff(want, cassandra.SplitStatements(cql)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if diff := cmp.Diff([]string{}, cassandra.SplitStatements(" ;\n-- nothing\n")); diff != "" {
+		t.Errorf("empty: mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,219 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+func validateStatement(config *Config) error {
+	switch config.Missing {
+	case "":
+		config.Missing = MissingNull
+	case MissingNull, MissingUnset:
+	default:
+		return fmt.Errorf("Unknown missing fields semantic: %s", config.Missing)
+	}
+
+	for _, c := range config.Columns {
+		if c.Name == "" {
+			return fmt.Errorf("Column name is mandatory")
+		}
+	}
+
+	if config.TTL.Duration != "" {
+		d, err := time.ParseDuration(config.TTL.Duration)
+		if err != nil {
+			return err
+		}
+		if d < time.Second {
+			return fmt.Errorf("TTL must be at least 1s")
+		}
+		config.TTL.seconds = int64(d / time.Second)
+	}
+
+	return nil
+}
+
+// BuildInsert builds the insert statement, built once and prepared by the
+// session on first use. The statement inserts the record as JSON, so that the
+// server converts values to the column types. When columns are configured the
+// bound JSON holds only the mapped columns and the other ones are left unset
+func BuildInsert(config Config) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "INSERT INTO %s.%s JSON ?", config.Keyspace, config.Table)
+	if config.Missing == MissingUnset || len(config.Columns) > 0 {
+		b.WriteString(" DEFAULT UNSET")
+	}
+
+	using := make([]string, 0, 2)
+	switch {
+	case config.TTL.Field != "":
+		using = append(using, "TTL ?")
+	case config.TTL.Duration != "":
+		using = append(using, fmt.Sprintf("TTL %d", config.TTL.seconds))
+	}
+	if config.Timestamp.Field != "" {
+		using = append(using, "TIMESTAMP ?")
+	}
+	if len(using) > 0 {
+		fmt.Fprintf(&b, " USING %s", strings.Join(using, " AND "))
+	}
+
+	return b.String()
+}
+
+// BindValues returns the values to bind to the statement built by BuildInsert
+func BindValues(config Config, v []byte) ([]interface{}, error) {
+	values := make([]interface{}, 0, 3)
+
+	var record map[string]interface{}
+	if len(config.Columns) > 0 || config.TTL.Field != "" || config.Timestamp.Field != "" {
+		d := json.NewDecoder(bytes.NewReader(v))
+		d.UseNumber()
+		if err := d.Decode(&record); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(config.Columns) == 0 {
+		values = append(values, string(v))
+	} else {
+		columns, err := projectColumns(config, record)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, columns)
+	}
+
+	if config.TTL.Field != "" {
+		ttl, err := ttlValue(config.TTL, record)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ttl)
+	}
+
+	if config.Timestamp.Field != "" {
+		ts, err := timestampValue(config.Timestamp, record)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, ts)
+	}
+
+	return values, nil
+}
+
+func ttlValue(config TTL, record map[string]interface{}) (interface{}, error) {
+	val, ok := lookup(record, config.Field)
+	if !ok || val == nil {
+		if config.seconds > 0 {
+			return config.seconds, nil
+		}
+		// an unset TTL is the same as no TTL
+		return gocql.UnsetValue, nil
+	}
+
+	n, ok := val.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("TTL field %s must be a number of seconds", config.Field)
+	}
+	seconds, err := n.Int64()
+	if err != nil {
+		return nil, fmt.Errorf("TTL field %s must be a number of seconds: %w", config.Field, err)
+	}
+	return seconds, nil
+}
+
+func timestampValue(config Timestamp, record map[string]interface{}) (interface{}, error) {
+	val, ok := lookup(record, config.Field)
+	if !ok || val == nil {
+		// an unset timestamp is the same as the server or client side one
+		return gocql.UnsetValue, nil
+	}
+
+	switch t := val.(type) {
+	case json.Number:
+		micros, err := t.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("Timestamp field %s must be microseconds since epoch: %w", config.Field, err)
+		}
+		return micros, nil
+	case string:
+		ts, err := time.Parse(time.RFC3339Nano, t)
+		if err != nil {
+			return nil, fmt.Errorf("Timestamp field %s must be an RFC 3339 date: %w", config.Field, err)
+		}
+		return ts.UnixMicro(), nil
+	default:
+		return nil, fmt.Errorf("Timestamp field %s must be microseconds since epoch or an RFC 3339 date", config.Field)
+	}
+}
+
+// lookup resolves a dotted path in the record
+func lookup(record map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = record
+	for _, segment := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// projectColumns returns the JSON object of the mapped columns, missing
+// fields are written as null or left out to be unset
+func projectColumns(config Config, record map[string]interface{}) (string, error) {
+	columns := make(map[string]interface{}, len(config.Columns))
+	for _, c := range config.Columns {
+		path := c.Path
+		if path == "" {
+			path = c.Name
+		}
+		val, ok := lookup(record, path)
+		if !ok && config.Missing == MissingUnset {
+			continue
+		}
+		columns[c.Name] = val
+	}
+
+	b, err := json.Marshal(columns)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
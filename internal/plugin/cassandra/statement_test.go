@@ -0,0 +1,146 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra_test
+
+import (
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/cassandra"
+)
+
+func TestBuildInsert(t *testing.T) {
+	tests := []struct {
+		name   string
+		config cassandra.Config
+		want   string
+	}{
+		{
+			name:   "json",
+			config: cassandra.Config{Keyspace: "ks", Table: "t", Missing: cassandra.MissingNull},
+			want:   "INSERT INTO ks.t JSON ?",
+		},
+		{
+			name:   "json default unset",
+			config: cassandra.Config{Keyspace: "ks", Table: "t", Missing: cassandra.MissingUnset},
+			want:   "INSERT INTO ks.t JSON ? DEFAULT UNSET",
+		},
+		{
+			name: "columns with ttl and timestamp",
+			config: cassandra.Config{
+				Keyspace:  "ks",
+				Table:     "t",
+				Columns:   []cassandra.Column{{Name: "id"}, {Name: "city", Path: "address.city"}},
+				TTL:       cassandra.TTL{Field: "ttl"},
+				Timestamp: cassandra.Timestamp{Field: "updated_at"},
+			},
+			want: "INSERT INTO ks.t JSON ? DEFAULT UNSET USING TTL ? AND TIMESTAMP ?",
+		},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, cassandra.BuildInsert(tt.config)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBindValues(t *testing.T) {
+	record := `{"id":1,"name":"jr","address":{"city":"Rome"},"ttl":60,"updated_at":"2024-01-02T03:04:05Z",` +
+		`"price":2,"ratio":0.5,"amount":12345678901234567890.123456789,"created_at":"2024-01-02 03:04:05+0000"}`
+
+	tests := []struct {
+		name   string
+		config cassandra.Config
+		want   []interface{}
+	}{
+		{
+			name:   "json",
+			config: cassandra.Config{},
+			want:   []interface{}{record},
+		},
+		{
+			name: "columns",
+			config: cassandra.Config{
+				Columns: []cassandra.Column{
+					{Name: "id"},
+					{Name: "city", Path: "address.city"},
+					{Name: "zip", Path: "address.zip"},
+				},
+				Missing: cassandra.MissingNull,
+			},
+			want: []interface{}{`{"city":"Rome","id":1,"zip":null}`},
+		},
+		{
+			name: "columns unset with ttl and timestamp",
+			config: cassandra.Config{
+				Columns: []cassandra.Column{
+					{Name: "id"},
+					{Name: "zip", Path: "address.zip"},
+				},
+				Missing:   cassandra.MissingUnset,
+				TTL:       cassandra.TTL{Field: "ttl"},
+				Timestamp: cassandra.Timestamp{Field: "updated_at"},
+			},
+			want: []interface{}{`{"id":1}`, int64(60), int64(1704164645000000)},
+		},
+		{
+			// numbers and dates are kept as in the record for the server to
+			// convert them to double, float, decimal and timestamp columns
+			name: "double float decimal and timestamp columns",
+			config: cassandra.Config{
+				Columns: []cassandra.Column{
+					{Name: "price"},
+					{Name: "ratio"},
+					{Name: "amount"},
+					{Name: "created_at"},
+				},
+				Missing: cassandra.MissingNull,
+			},
+			want: []interface{}{`{"amount":12345678901234567890.123456789,"created_at":"2024-01-02 03:04:05+0000","price":2,"ratio":0.5}`},
+		},
+		{
+			name: "missing ttl",
+			config: cassandra.Config{
+				TTL: cassandra.TTL{Field: "expires_in"},
+			},
+			want: []interface{}{record, gocql.UnsetValue},
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := cassandra.BindValues(tt.config, []byte(record))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+
+	if _, err := cassandra.BindValues(cassandra.Config{TTL: cassandra.TTL{Field: "name"}}, []byte(record)); err == nil {
+		t.Errorf("expected an error for a non numeric TTL")
+	}
+}
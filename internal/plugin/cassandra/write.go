@@ -0,0 +1,352 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultBatchSize       = 20
+	DefaultBatchMaxPending = 1000
+	DefaultBatchMaxAge     = 5 * time.Second
+	DefaultMaxInFlight     = 64
+)
+
+func validateMode(config *Config) error {
+	switch config.Mode {
+	case "":
+		config.Mode = SyncMode
+	case SyncMode:
+	case BatchMode:
+		if len(config.Batch.PartitionKey) == 0 {
+			return fmt.Errorf("Partition key is mandatory in %s mode", BatchMode)
+		}
+		if config.Batch.Size == 0 {
+			config.Batch.Size = DefaultBatchSize
+		}
+		if config.Batch.Size < 0 {
+			return fmt.Errorf("Batch size must be positive")
+		}
+		if config.Batch.MaxPending == 0 {
+			config.Batch.MaxPending = max(DefaultBatchMaxPending, config.Batch.Size)
+		}
+		if config.Batch.MaxPending < config.Batch.Size {
+			return fmt.Errorf("Max pending must be at least the batch size")
+		}
+		maxAge, err := parseDuration("batch max age", config.Batch.MaxAge, DefaultBatchMaxAge)
+		if err != nil {
+			return err
+		}
+		if maxAge <= 0 {
+			return fmt.Errorf("Batch max age must be positive")
+		}
+		config.Batch.maxAge = maxAge
+	case AsyncMode:
+		if config.Async.MaxInFlight == 0 {
+			config.Async.MaxInFlight = DefaultMaxInFlight
+		}
+		if config.Async.MaxInFlight < 0 {
+			return fmt.Errorf("Max in flight must be positive")
+		}
+	default:
+		return fmt.Errorf("Unknown mode: %s", config.Mode)
+	}
+	return nil
+}
+
+// PartitionKeyOf returns the partition key of the record, made of the values
+// found at the given paths, used to group records of the same partition
+func PartitionKeyOf(paths []string, v []byte) (string, error) {
+	var record map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(v))
+	d.UseNumber()
+	if err := d.Decode(&record); err != nil {
+		return "", err
+	}
+
+	values := make([]interface{}, len(paths))
+	for i, path := range paths {
+		val, ok := lookup(record, path)
+		if !ok {
+			return "", fmt.Errorf("Partition key field %s is missing", path)
+		}
+		values[i] = val
+	}
+
+	key, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(key), nil
+}
+
+// batchExecutor writes the rows of a partition as a single batch
+type batchExecutor func(ctx context.Context, rows [][]interface{}) error
+
+// inserter writes the values of a single record
+type inserter func(ctx context.Context, values []interface{}) error
+
+func sessionBatch(session *gocql.Session, consistency gocql.Consistency, spec gocql.SpeculativeExecutionPolicy, statement string) batchExecutor {
+	return func(ctx context.Context, rows [][]interface{}) error {
+		batch := session.NewBatch(gocql.UnloggedBatch).WithContext(ctx)
+		batch.SetConsistency(consistency)
+		batch.SpeculativeExecutionPolicy(spec)
+		for _, values := range rows {
+			batch.Query(statement, values...)
+		}
+		return session.ExecuteBatch(batch)
+	}
+}
+
+func sessionInsert(session *gocql.Session, consistency gocql.Consistency, spec gocql.SpeculativeExecutionPolicy, statement string) inserter {
+	return func(ctx context.Context, values []interface{}) error {
+		return session.Query(statement, values...).
+			WithContext(ctx).
+			Consistency(consistency).
+			SetSpeculativeExecutionPolicy(spec).
+			Exec()
+	}
+}
+
+// batchGroup holds the rows buffered for a partition
+type batchGroup struct {
+	rows    [][]interface{}
+	created time.Time
+}
+
+// batchWriter groups records into unlogged batches per partition key, so that
+// every batch is handled by a single replica set. A partition is written when
+// it reaches the batch size or MaxAge, and the largest partition is written
+// when the records buffered across all partitions reach MaxPending, so that
+// keys with a high cardinality do not grow the buffer without bound
+type batchWriter struct {
+	mu sync.Mutex
+
+	execute    batchExecutor
+	size       int
+	maxPending int
+	maxAge     time.Duration
+	groups     map[string]*batchGroup
+	pending    int
+
+	ticker *time.Ticker
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	// failures of the age flush, returned by the next Add or by Close
+	errs []error
+}
+
+func newBatchWriter(execute batchExecutor, config Batch) *batchWriter {
+	b := &batchWriter{
+		execute:    execute,
+		size:       config.Size,
+		maxPending: config.MaxPending,
+		maxAge:     config.maxAge,
+		groups:     make(map[string]*batchGroup),
+		done:       make(chan struct{}),
+	}
+
+	b.ticker = time.NewTicker(max(b.maxAge/2, time.Millisecond))
+	b.wg.Add(1)
+	go b.flushExpired()
+
+	return b
+}
+
+// Add buffers the values and writes the batch of the partition once it is
+// full, or the largest batch once MaxPending records are buffered
+func (b *batchWriter) Add(ctx context.Context, key string, values []interface{}) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	group, ok := b.groups[key]
+	if !ok {
+		group = &batchGroup{created: time.Now()}
+		b.groups[key] = group
+	}
+	group.rows = append(group.rows, values)
+	b.pending++
+
+	var message string
+	var err error
+	switch {
+	case len(group.rows) >= b.size:
+		message, err = b.flush(ctx, key)
+	case b.pending >= b.maxPending:
+		message, err = b.flush(ctx, b.largest())
+	}
+	if err != nil {
+		return "", err
+	}
+	return message, b.takeErrors()
+}
+
+// Close stops the age flush and writes the batches of all the partitions
+func (b *batchWriter) Close(ctx context.Context) error {
+	close(b.done)
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.groups {
+		if _, err := b.flush(ctx, key); err != nil {
+			b.errs = append(b.errs, err)
+		}
+	}
+	err := errors.Join(b.errs...)
+	b.errs = nil
+	return err
+}
+
+// flushExpired writes the partitions buffered for longer than MaxAge
+func (b *batchWriter) flushExpired() {
+	defer b.wg.Done()
+	defer b.ticker.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case now := <-b.ticker.C:
+			b.mu.Lock()
+			for key, group := range b.groups {
+				if now.Sub(group.created) < b.maxAge {
+					continue
+				}
+				if _, err := b.flush(context.Background(), key); err != nil {
+					log.Warn().Err(err).Msg("Failed to write expired batch")
+					b.errs = append(b.errs, err)
+				}
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// largest returns the key of the partition with the most buffered records
+func (b *batchWriter) largest() string {
+	var key string
+	size := 0
+	for k, group := range b.groups {
+		if len(group.rows) > size {
+			key, size = k, len(group.rows)
+		}
+	}
+	return key
+}
+
+func (b *batchWriter) flush(ctx context.Context, key string) (string, error) {
+	group, ok := b.groups[key]
+	if !ok {
+		return "", nil
+	}
+	delete(b.groups, key)
+	b.pending -= len(group.rows)
+
+	if err := b.execute(ctx, group.rows); err != nil {
+		return "", fmt.Errorf("batch of %d records for partition %s failed: %w", len(group.rows), key, err)
+	}
+	return fmt.Sprintf("batch of %d records written", len(group.rows)), nil
+}
+
+func (b *batchWriter) takeErrors() error {
+	err := errors.Join(b.errs...)
+	b.errs = nil
+	if err != nil {
+		return fmt.Errorf("Record buffered, but previous batches failed: %w", err)
+	}
+	return nil
+}
+
+// asyncWriter executes inserts concurrently, at most maxInFlight at a time.
+// Errors are reported on the next write, once its insert is started, or when closing
+type asyncWriter struct {
+	mu sync.Mutex
+	wg sync.WaitGroup
+
+	insert   inserter
+	inFlight chan struct{}
+	errs     []error
+}
+
+func newAsyncWriter(insert inserter, config Async) *asyncWriter {
+	return &asyncWriter{
+		insert:   insert,
+		inFlight: make(chan struct{}, config.MaxInFlight),
+	}
+}
+
+// Write executes the insert in background, blocking while the in-flight limit
+// is reached. The record is always written, failures of previous inserts are
+// returned alongside
+func (w *asyncWriter) Write(ctx context.Context, values []interface{}) error {
+	w.inFlight <- struct{}{}
+	w.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-w.inFlight
+			w.wg.Done()
+		}()
+
+		if err := w.insert(ctx, values); err != nil {
+			log.Debug().Err(err).Msg("Async insert failed")
+			w.mu.Lock()
+			w.errs = append(w.errs, err)
+			w.mu.Unlock()
+		}
+	}()
+
+	if err := w.takeErrors(); err != nil {
+		return fmt.Errorf("Record queued, but previous inserts failed: %w", err)
+	}
+	return nil
+}
+
+// Close waits for the in-flight inserts
+func (w *asyncWriter) Close() error {
+	w.wg.Wait()
+	return w.takeErrors()
+}
+
+func (w *asyncWriter) takeErrors() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := errors.Join(w.errs...)
+	w.errs = nil
+	return err
+}
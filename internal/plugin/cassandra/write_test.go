@@ -0,0 +1,304 @@
+//go:build cassandra
+// +build cassandra
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cassandra_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/cassandra"
+)
+
+func TestPartitionKeyOf(t *testing.T) {
+	paths := []string{"tenant", "device.id"}
+
+	a, err := cassandra.PartitionKeyOf(paths, []byte(`{"tenant":"acme","device":{"id":1},"value":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := cassandra.PartitionKeyOf(paths, []byte(`{"value":2,"device":{"id":1},"tenant":"acme"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(a, b); diff != "" {
+		t.Errorf("same partition: mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(`["acme",1]`, a); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	c, err := cassandra.PartitionKeyOf(paths, []byte(`{"tenant":"acme","device":{"id":2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == c {
+		t.Errorf("expected different partitions, got %s", c)
+	}
+
+	if _, err := cassandra.PartitionKeyOf(paths, []byte(`{"tenant":"acme"}`)); err == nil {
+		t.Errorf("expected an error for a missing partition key field")
+	}
+}
+
+// fakeSession records the rows written per batch
+type fakeSession struct {
+	mu      sync.Mutex
+	batches [][]interface{}
+	err     error
+}
+
+func (f *fakeSession) execute(_ context.Context, rows [][]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	batch := make([]interface{}, len(rows))
+	for i, values := range rows {
+		batch[i] = values[0]
+	}
+	f.batches = append(f.batches, batch)
+	return f.err
+}
+
+func (f *fakeSession) insert(ctx context.Context, values []interface{}) error {
+	return f.execute(ctx, [][]interface{}{values})
+}
+
+func (f *fakeSession) written() [][]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]interface{}(nil), f.batches...)
+}
+
+func newBatchWriter(t *testing.T, batch cassandra.Batch) (*cassandra.BatchWriter, *fakeSession) {
+	config := cassandra.Config{Mode: cassandra.BatchMode, Batch: batch}
+	if err := cassandra.ValidateMode(&config); err != nil {
+		t.Fatal(err)
+	}
+	session := &fakeSession{}
+	return cassandra.NewBatchWriter(session.execute, config.Batch), session
+}
+
+func TestValidateMode(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    cassandra.Config
+		wantBatch cassandra.Batch
+		wantAsync cassandra.Async
+		wantErr   bool
+	}{
+		{
+			name:      "sync by default",
+			config:    cassandra.Config{},
+			wantBatch: cassandra.Batch{},
+		},
+		{
+			name:      "batch defaults",
+			config:    cassandra.Config{Mode: cassandra.BatchMode, Batch: cassandra.Batch{PartitionKey: []string{"id"}}},
+			wantBatch: cassandra.Batch{Size: 20, PartitionKey: []string{"id"}, MaxPending: 1000},
+		},
+		{
+			name:      "max pending defaults to the batch size",
+			config:    cassandra.Config{Mode: cassandra.BatchMode, Batch: cassandra.Batch{Size: 5000, PartitionKey: []string{"id"}}},
+			wantBatch: cassandra.Batch{Size: 5000, PartitionKey: []string{"id"}, MaxPending: 5000},
+		},
+		{
+			name:    "missing partition key",
+			config:  cassandra.Config{Mode: cassandra.BatchMode},
+			wantErr: true,
+		},
+		{
+			name:    "max pending below the batch size",
+			config:  cassandra.Config{Mode: cassandra.BatchMode, Batch: cassandra.Batch{Size: 20, MaxPending: 10, PartitionKey: []string{"id"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid max age",
+			config:  cassandra.Config{Mode: cassandra.BatchMode, Batch: cassandra.Batch{MaxAge: "soon", PartitionKey: []string{"id"}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative max age",
+			config:  cassandra.Config{Mode: cassandra.BatchMode, Batch: cassandra.Batch{MaxAge: "-1s", PartitionKey: []string{"id"}}},
+			wantErr: true,
+		},
+		{
+			name:      "async defaults",
+			config:    cassandra.Config{Mode: cassandra.AsyncMode},
+			wantAsync: cassandra.Async{MaxInFlight: 64},
+		},
+		{
+			name:    "unknown mode",
+			config:  cassandra.Config{Mode: "eventually"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := cassandra.ValidateMode(&tt.config)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.wantBatch, tt.config.Batch, cmpopts.IgnoreUnexported(cassandra.Batch{})); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if diff := cmp.Diff(tt.wantAsync, tt.config.Async); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBatchWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		batch    cassandra.Batch
+		keys     []string
+		want     [][]interface{}
+		messages []string
+	}{
+		{
+			name:     "partition full",
+			batch:    cassandra.Batch{Size: 2, PartitionKey: []string{"id"}, MaxAge: "1h"},
+			keys:     []string{"a", "b", "a", "b"},
+			want:     [][]interface{}{{0, 2}, {1, 3}},
+			messages: []string{"", "", "batch of 2 records written", "batch of 2 records written"},
+		},
+		{
+			name:     "max pending writes the largest partition",
+			batch:    cassandra.Batch{Size: 3, MaxPending: 4, PartitionKey: []string{"id"}, MaxAge: "1h"},
+			keys:     []string{"a", "b", "a", "c", "d"},
+			want:     [][]interface{}{{0, 2}},
+			messages: []string{"", "", "", "batch of 2 records written", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		w, session := newBatchWriter(t, tt.batch)
+
+		messages := make([]string, 0)
+		for i, key := range tt.keys {
+			message, err := w.Add(context.Background(), key, []interface{}{i})
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			messages = append(messages, message)
+		}
+
+		if diff := cmp.Diff(tt.want, session.written()); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+		if diff := cmp.Diff(tt.messages, messages); diff != "" {
+			t.Errorf("%s: messages: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+
+		if err := w.Close(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		records := 0
+		for _, batch := range session.written() {
+			records += len(batch)
+		}
+		if diff := cmp.Diff(len(tt.keys), records); diff != "" {
+			t.Errorf("%s: records: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBatchWriterMaxAge(t *testing.T) {
+	w, session := newBatchWriter(t, cassandra.Batch{Size: 10, PartitionKey: []string{"id"}, MaxAge: "10ms"})
+	session.err = errors.New("timeout")
+
+	if _, err := w.Add(context.Background(), "a", []interface{}{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(session.written()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if diff := cmp.Diff([][]interface{}{{0}}, session.written()); diff != "" {
+		t.Fatalf("mismatch (-want +got):\n%s", diff)
+	}
+
+	// the failed batch is reported by the next add, which still buffers its record
+	session.mu.Lock()
+	session.err = nil
+	session.mu.Unlock()
+
+	if _, err := w.Add(context.Background(), "a", []interface{}{1}); err == nil {
+		t.Errorf("expected the batch error")
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([][]interface{}{{0}, {1}}, session.written()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAsyncWriter(t *testing.T) {
+	session := &fakeSession{}
+	failing := func(ctx context.Context, values []interface{}) error {
+		err := session.insert(ctx, values)
+		if values[0] == 0 {
+			return errors.New("unavailable")
+		}
+		return err
+	}
+	w := cassandra.NewAsyncWriter(failing, cassandra.Async{MaxInFlight: 1})
+
+	ctx := context.Background()
+	if err := w.Write(ctx, []interface{}{0}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the failure is reported by a later write, which is still executed
+	deadline := time.Now().Add(time.Second)
+	records := 1
+	var err error
+	for err == nil && time.Now().Before(deadline) {
+		err = w.Write(ctx, []interface{}{records})
+		records++
+	}
+	if err == nil {
+		t.Fatalf("expected the previous insert error")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(records, len(session.written())); diff != "" {
+		t.Errorf("records: mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build gcs
+// +build gcs
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gcs
+
+import (
+	"github.com/jrnd-io/jr-plugins/internal/mapping"
+	"github.com/jrnd-io/jr-plugins/internal/rolling"
+)
+
+type BodyFormat string
+
+const (
+	RawBody      BodyFormat = "raw"
+	KeyValueBody BodyFormat = "key_value"
+)
+
+type Mode string
+
+const (
+	ObjectMode  Mode = "object"
+	RollingMode Mode = "rolling"
+)
+
+type Format string
+
+const (
+	NDJSONFormat Format = "ndjson"
+	CSVFormat    Format = "csv"
+)
+
+type Compression string
+
+const (
+	NoCompression   Compression = "none"
+	GzipCompression Compression = "gzip"
+)
+
+type CSV struct {
+	Columns   []string `json:"columns"`
+	Header    bool     `json:"header"`
+	Delimiter string   `json:"delimiter"`
+}
+
+type Rolling struct {
+	Prefix      string      `json:"prefix"`
+	Format      Format      `json:"format"`
+	Compression Compression `json:"compression"`
+	MaxBytes    int64       `json:"max_bytes"`
+	MaxRecords  int         `json:"max_records"`
+	MaxAge      string      `json:"max_age"`
+	CSV         CSV         `json:"csv"`
+	limits      rolling.Limits
+}
+
+type Config struct {
+	Bucket          string `json:"bucket_name"`
+	CreateBucket    bool   `json:"create_bucket"`
+	ProjectID       string `json:"project_id"`
+	CredentialsFile string `json:"credentials_file"`
+	Endpoint        string `json:"endpoint"`
+
+	Mode        Mode            `json:"mode"`
+	Rolling     Rolling         `json:"rolling"`
+	BodyFormat  BodyFormat      `json:"body_format"`
+	ContentType string          `json:"content_type"`
+	KeyTemplate string          `json:"key_template"`
+	Metadata    mapping.Mapping `json:"metadata"`
+}
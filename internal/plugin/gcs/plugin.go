@@ -24,15 +24,20 @@
 package gcs
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"text/template"
+	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/google/uuid"
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
+	"google.golang.org/api/option"
 )
 
 const (
@@ -43,13 +48,22 @@ func init() {
 	plugin.RegisterPlugin(Name, &Plugin{})
 }
 
-type Config struct {
-	Bucket string `json:"bucket_name"`
+type Plugin struct {
+	configuration Config
+
+	client      *storage.Client
+	bucket      string
+	keyTemplate *template.Template
+	rolling     *rollingWriter
 }
 
-type Plugin struct {
-	client storage.Client
-	bucket string
+// KeyData is the data available to the object key template
+type KeyData struct {
+	Key     string
+	UUID    string
+	Time    time.Time
+	Value   map[string]interface{}
+	Headers map[string]string
 }
 
 func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
@@ -59,20 +73,115 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 		return err
 	}
 
-	// Use Google Application Default Credentials to authorize and authenticate the client.
+	if config.Bucket == "" {
+		return fmt.Errorf("Bucket is mandatory")
+	}
+
+	switch config.BodyFormat {
+	case "":
+		config.BodyFormat = RawBody
+	case RawBody, KeyValueBody:
+	default:
+		return fmt.Errorf("Unknown body format: %s", config.BodyFormat)
+	}
+
+	switch config.Mode {
+	case "":
+		config.Mode = ObjectMode
+	case ObjectMode:
+	case RollingMode:
+		if err := validateRolling(&config.Rolling); err != nil {
+			return err
+		}
+		// records are written one per line into objects named after the rolling prefix
+		if config.KeyTemplate != "" {
+			return fmt.Errorf("KeyTemplate is not supported in %s mode", RollingMode)
+		}
+		if config.BodyFormat == KeyValueBody {
+			return fmt.Errorf("Body format %s is not supported in %s mode", KeyValueBody, RollingMode)
+		}
+		if config.Metadata.FromHeaders {
+			return fmt.Errorf("Metadata from headers is not supported in %s mode", RollingMode)
+		}
+	default:
+		return fmt.Errorf("Unknown mode: %s", config.Mode)
+	}
+
+	p.keyTemplate, err = ParseKeyTemplate(config.KeyTemplate)
+	if err != nil {
+		return err
+	}
+
+	if config.CreateBucket && config.ProjectID == "" {
+		return fmt.Errorf("ProjectID is mandatory when CreateBucket is set")
+	}
+
+	// Unless a credentials file is given, use Google Application Default Credentials
+	// to authorize and authenticate the client.
 	// More information about Application Default Credentials and how to enable is at
 	// https://developers.google.com/identity/protocols/application-default-credentials.
-	client, err := storage.NewClient(ctx)
+	opts := make([]option.ClientOption, 0)
+	if config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsFile))
+	}
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+		// emulators such as fake-gcs-server do not authenticate requests
+		if config.CredentialsFile == "" {
+			opts = append(opts, option.WithoutAuthentication())
+		}
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return err
 	}
 
-	p.client = *client
+	if config.CreateBucket {
+		if err := createBucket(ctx, client, config.Bucket, config.ProjectID); err != nil {
+			client.Close()
+			return err
+		}
+	}
+
+	p.client = client
 	p.bucket = config.Bucket
+	p.configuration = config
+
+	if config.Mode == RollingMode {
+		p.rolling = newRollingWriter(config.Rolling, storageObjects(
+			client,
+			config.Bucket,
+			rollingContentType(config.Rolling, config.ContentType),
+			config.Metadata.Static))
+	}
 	return nil
 }
 
+func createBucket(ctx context.Context, client *storage.Client, bucket string, projectID string) error {
+	handle := client.Bucket(bucket)
+	_, err := handle.Attrs(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, storage.ErrBucketNotExist) {
+		return err
+	}
+	return handle.Create(ctx, projectID, nil)
+}
+
 func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.ProduceResponse, error) {
+	if p.rolling != nil {
+		b, object, err := p.rolling.Write(v)
+		if err != nil {
+			return nil, err
+		}
+		return &jrpc.ProduceResponse{
+			Bytes:   uint64(b),
+			Message: object,
+		}, nil
+	}
+
 	bucket := p.bucket
 	var key string
 
@@ -83,25 +192,87 @@ func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.P
 		key = string(k)
 	}
 
-	objectHandle := p.client.Bucket(bucket).Object(key)
-	writer := objectHandle.NewWriter(context.Background())
-	kvPair := fmt.Sprintf("%s=%s\n", key, v)
+	objectName, err := ObjectName(p.keyTemplate, key, v, headers)
+	if err != nil {
+		return nil, err
+	}
 
-	b, err := writer.Write([]byte(kvPair))
+	var body []byte
+	switch p.configuration.BodyFormat {
+	case KeyValueBody:
+		body = []byte(fmt.Sprintf("%s=%s\n", key, v))
+	default:
+		body = v
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writer := p.client.Bucket(bucket).Object(objectName).NewWriter(ctx)
+	writer.ContentType = p.configuration.ContentType
+	writer.Metadata = p.configuration.Metadata.Values(headers)
+
+	b, err := writer.Write(body)
 	if err != nil {
+		// cancelling the context aborts the upload
+		cancel()
+		_ = writer.Close()
 		return nil, err
 	}
 
-	writer.Close()
+	// upload errors are reported by Close
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
 
 	return &jrpc.ProduceResponse{
 		Bytes:   uint64(b),
-		Message: "",
+		Message: objectName,
 	}, nil
 
 }
 
+// ParseKeyTemplate parses the object key template, fields missing from the
+// record make the rendering fail instead of producing "<no value>"
+func ParseKeyTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	return template.New("key").Option("missingkey=error").Parse(text)
+}
+
+// ObjectName renders the key template for the record, the key is used as is
+// when there is no template
+func ObjectName(tmpl *template.Template, key string, v []byte, headers map[string]string) (string, error) {
+	if tmpl == nil {
+		return key, nil
+	}
+
+	data := KeyData{
+		Key:     key,
+		UUID:    uuid.New().String(),
+		Time:    time.Now().UTC(),
+		Headers: headers,
+	}
+	// values that are not JSON objects are simply not available to the template
+	_ = json.Unmarshal(v, &data.Value)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("Key template rendered an empty object name")
+	}
+	return buf.String(), nil
+}
+
 func (p *Plugin) Close(_ context.Context) error {
-	p.client.Close()
-	return nil
+	if p.rolling != nil {
+		if err := p.rolling.Close(); err != nil {
+			p.client.Close()
+			return err
+		}
+	}
+	return p.client.Close()
 }
@@ -0,0 +1,133 @@
+//go:build gcs
+// +build gcs
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gcs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/gcs"
+)
+
+func TestObjectName(t *testing.T) {
+	today := time.Now().UTC().Format("2006/01/02")
+
+	tests := []struct {
+		name     string
+		template string
+		value    string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:  "no template",
+			value: `{"id":"1"}`,
+			want:  "k1",
+		},
+		{
+			name:     "date partitions and fields",
+			template: `{{.Time.Format "2006/01/02"}}/{{.Value.tenant}}/{{.Key}}.json`,
+			value:    `{"id":"1","tenant":"acme"}`,
+			want:     today + "/acme/k1.json",
+		},
+		{
+			name:     "headers",
+			template: `{{.Headers.source}}/{{.Key}}`,
+			value:    `{"id":"1"}`,
+			want:     "jr/k1",
+		},
+		{
+			name:     "missing field",
+			template: `a/{{.Value.missing}}/b`,
+			value:    `{"id":"1"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "value not an object",
+			template: `a/{{.Value.id}}/b`,
+			value:    `"not an object"`,
+			wantErr:  true,
+		},
+		{
+			name:     "empty name",
+			template: `{{if false}}x{{end}}`,
+			value:    `{"id":"1"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		tmpl, err := gcs.ParseKeyTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		got, err := gcs.ObjectName(tmpl, "k1", []byte(tt.value), map[string]string{"source": "jr"})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestInitRejectsRollingOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{
+			name:   "key template",
+			config: `{"bucket_name":"b","mode":"rolling","rolling":{"max_records":10},"key_template":"{{.Key}}"}`,
+		},
+		{
+			name:   "key value body",
+			config: `{"bucket_name":"b","mode":"rolling","rolling":{"max_records":10},"body_format":"key_value"}`,
+		},
+		{
+			name:   "metadata from headers",
+			config: `{"bucket_name":"b","mode":"rolling","rolling":{"max_records":10},"metadata":{"from_headers":true}}`,
+		},
+		{
+			name:   "no limit",
+			config: `{"bucket_name":"b","mode":"rolling"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		p := &gcs.Plugin{}
+		if err := p.Init(context.Background(), []byte(tt.config)); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
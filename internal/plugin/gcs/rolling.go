@@ -0,0 +1,344 @@
+//go:build gcs
+// +build gcs
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gcs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/storage"
+	"github.com/jrnd-io/jr-plugins/internal/rolling"
+	"github.com/rs/zerolog/log"
+)
+
+// objectFactory creates the writer uploading an object, cancelling the context
+// aborts the upload
+type objectFactory func(ctx context.Context, name string) io.WriteCloser
+
+func storageObjects(client *storage.Client, bucket string, contentType string, metadata map[string]string) objectFactory {
+	return func(ctx context.Context, name string) io.WriteCloser {
+		writer := client.Bucket(bucket).Object(name).NewWriter(ctx)
+		writer.ContentType = contentType
+		writer.Metadata = metadata
+		return writer
+	}
+}
+
+// rollingWriter streams records into the current object through a single upload.
+// The size limit applies to uncompressed bytes.
+type rollingWriter struct {
+	mu sync.Mutex
+
+	newObject objectFactory
+	config    Rolling
+
+	seq     int
+	object  string
+	cancel  context.CancelFunc
+	writer  io.WriteCloser
+	gz      *gzip.Writer
+	out     *countingWriter
+	csv     *csv.Writer
+	columns []string
+	records int
+	timer   *time.Timer
+
+	// finalizing from the age timer has no caller to report to, the error
+	// is returned by the next Write after its record is written
+	rollErr error
+}
+
+type countingWriter struct {
+	w     io.Writer
+	count int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.count += int64(n)
+	return n, err
+}
+
+func validateRolling(config *Rolling) error {
+	var err error
+
+	switch config.Format {
+	case "":
+		config.Format = NDJSONFormat
+	case NDJSONFormat, CSVFormat:
+	default:
+		return fmt.Errorf("Unknown rolling format: %s", config.Format)
+	}
+
+	switch config.Compression {
+	case "":
+		config.Compression = NoCompression
+	case NoCompression, GzipCompression:
+	default:
+		return fmt.Errorf("Unknown rolling compression: %s", config.Compression)
+	}
+
+	if config.CSV.Delimiter != "" && utf8.RuneCountInString(config.CSV.Delimiter) != 1 {
+		return fmt.Errorf("CSV delimiter must be a single character")
+	}
+
+	config.limits, err = rolling.ParseLimits(config.MaxBytes, config.MaxRecords, config.MaxAge)
+	return err
+}
+
+func rollingContentType(config Rolling, contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if config.Format == CSVFormat {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+func newRollingWriter(config Rolling, newObject objectFactory) *rollingWriter {
+	return &rollingWriter{
+		newObject: newObject,
+		config:    config,
+		columns:   config.CSV.Columns,
+	}
+}
+
+// Write appends the record to the current object and returns the number of
+// uncompressed bytes written and the name of the object
+func (w *rollingWriter) Write(v []byte) (int, string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var row []string
+	var err error
+	if w.config.Format == CSVFormat {
+		row, err = w.csvRow(v)
+	} else {
+		v, err = rolling.JSONLine(v)
+	}
+	if err != nil {
+		return 0, "", err
+	}
+
+	if w.writer == nil {
+		w.open()
+	}
+
+	before := w.out.count
+	if err := w.writeRecord(v, row); err != nil {
+		w.abort()
+		return 0, "", err
+	}
+	w.records++
+
+	written := int(w.out.count - before)
+	object := w.object
+
+	if w.config.limits.Reached(w.out.count, w.records) {
+		if err := w.finalize(); err != nil {
+			return written, object, err
+		}
+	}
+
+	return written, object, w.takeRollErr()
+}
+
+// takeRollErr returns the finalize failure of the age timer, if any, once the
+// current record has been written
+func (w *rollingWriter) takeRollErr() error {
+	err := w.rollErr
+	w.rollErr = nil
+	if err != nil {
+		return fmt.Errorf("Record written, but a previous rolling object failed to finalize: %w", err)
+	}
+	return nil
+}
+
+// Close finalizes the current object, if any
+func (w *rollingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.finalize()
+}
+
+func (w *rollingWriter) open() {
+	w.seq++
+	w.object = w.objectName()
+	w.records = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.writer = w.newObject(ctx, w.object)
+
+	var out io.Writer = w.writer
+	if w.config.Compression == GzipCompression {
+		w.gz = gzip.NewWriter(w.writer)
+		out = w.gz
+	}
+	w.out = &countingWriter{w: out}
+
+	if w.config.Format == CSVFormat {
+		w.csv = csv.NewWriter(w.out)
+		if w.config.CSV.Delimiter != "" {
+			w.csv.Comma, _ = utf8.DecodeRuneInString(w.config.CSV.Delimiter)
+		}
+	}
+
+	if w.config.limits.MaxAge > 0 {
+		object := w.object
+		w.timer = time.AfterFunc(w.config.limits.MaxAge, func() {
+			w.finalizeExpired(object)
+		})
+	}
+
+	log.Debug().Str("object", w.object).Msg("Opened rolling object")
+}
+
+// finalizeExpired runs on the age timer, object is ignored when it has
+// been replaced in the meantime
+func (w *rollingWriter) finalizeExpired(object string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil || w.object != object {
+		return
+	}
+	if err := w.finalize(); err != nil {
+		log.Warn().Err(err).Str("object", object).Msg("Failed to finalize rolling object")
+		w.rollErr = err
+	}
+}
+
+func (w *rollingWriter) objectName() string {
+	ext := "." + string(w.config.Format)
+	if w.config.Compression == GzipCompression {
+		ext += ".gz"
+	}
+	return rolling.Name(w.config.Prefix, time.Now(), w.seq, ext)
+}
+
+func (w *rollingWriter) csvRow(v []byte) ([]string, error) {
+	var record map[string]interface{}
+	if err := json.Unmarshal(v, &record); err != nil {
+		return nil, err
+	}
+
+	// without explicit columns, the first record defines them
+	if len(w.columns) == 0 {
+		for k := range record {
+			w.columns = append(w.columns, k)
+		}
+		sort.Strings(w.columns)
+	}
+
+	row := make([]string, len(w.columns))
+	for i, c := range w.columns {
+		switch value := record[c].(type) {
+		case nil:
+			row[i] = ""
+		case string:
+			row[i] = value
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			row[i] = string(b)
+		}
+	}
+	return row, nil
+}
+
+func (w *rollingWriter) writeRecord(v []byte, row []string) error {
+	if w.config.Format != CSVFormat {
+		_, err := w.out.Write(v)
+		return err
+	}
+
+	if w.records == 0 && w.config.CSV.Header {
+		if err := w.csv.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	if err := w.csv.Write(row); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *rollingWriter) finalize() error {
+	if w.writer == nil {
+		return nil
+	}
+	defer w.reset()
+
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			w.cancel()
+			_ = w.writer.Close()
+			return err
+		}
+	}
+
+	// upload errors are reported by Close
+	if err := w.writer.Close(); err != nil {
+		return err
+	}
+
+	log.Debug().Str("object", w.object).Int("records", w.records).Msg("Finalized rolling object")
+	return nil
+}
+
+func (w *rollingWriter) abort() {
+	if w.writer == nil {
+		return
+	}
+	// cancelling the context aborts the upload
+	w.cancel()
+	_ = w.writer.Close()
+	w.reset()
+}
+
+func (w *rollingWriter) reset() {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.cancel()
+	w.writer = nil
+	w.gz = nil
+	w.csv = nil
+	w.out = nil
+	w.timer = nil
+	w.cancel = nil
+}
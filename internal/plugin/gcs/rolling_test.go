@@ -0,0 +1,397 @@
+//go:build gcs
+// +build gcs
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package gcs_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/gcs"
+)
+
+// fakeObjects keeps the uploaded objects in memory
+type fakeObjects struct {
+	mu       sync.Mutex
+	names    []string
+	objects  map[string]*fakeObject
+	closeErr error
+}
+
+type fakeObject struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	closed   bool
+	closeErr error
+}
+
+func (o *fakeObject) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.Write(p)
+}
+
+func (o *fakeObject) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	return o.closeErr
+}
+
+func (o *fakeObject) state() (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String(), o.closed
+}
+
+func (f *fakeObjects) create(_ context.Context, name string) io.WriteCloser {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.objects == nil {
+		f.objects = make(map[string]*fakeObject)
+	}
+	o := &fakeObject{closeErr: f.closeErr}
+	f.names = append(f.names, name)
+	f.objects[name] = o
+	return o
+}
+
+func (f *fakeObjects) contents() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	contents := make([]string, len(f.names))
+	for i, name := range f.names {
+		contents[i], _ = f.objects[name].state()
+	}
+	return contents
+}
+
+func (f *fakeObjects) closed() []bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	closed := make([]bool, len(f.names))
+	for i, name := range f.names {
+		_, closed[i] = f.objects[name].state()
+	}
+	return closed
+}
+
+func newRollingWriter(t *testing.T, config gcs.Rolling) (*gcs.RollingWriter, *fakeObjects) {
+	if err := gcs.ValidateRolling(&config); err != nil {
+		t.Fatal(err)
+	}
+	objects := &fakeObjects{}
+	return gcs.NewRollingWriter(config, objects.create), objects
+}
+
+func TestValidateRolling(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  gcs.Rolling
+		want    gcs.Rolling
+		wantErr bool
+	}{
+		{
+			name:   "defaults",
+			config: gcs.Rolling{MaxRecords: 10},
+			want:   gcs.Rolling{MaxRecords: 10, Format: gcs.NDJSONFormat, Compression: gcs.NoCompression},
+		},
+		{
+			name:   "csv gzip",
+			config: gcs.Rolling{MaxAge: "1m", Format: gcs.CSVFormat, Compression: gcs.GzipCompression, CSV: gcs.CSV{Delimiter: ";"}},
+			want:   gcs.Rolling{MaxAge: "1m", Format: gcs.CSVFormat, Compression: gcs.GzipCompression, CSV: gcs.CSV{Delimiter: ";"}},
+		},
+		{
+			name:    "no limit",
+			config:  gcs.Rolling{},
+			wantErr: true,
+		},
+		{
+			name:    "invalid age",
+			config:  gcs.Rolling{MaxAge: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			config:  gcs.Rolling{MaxRecords: 10, Format: "parquet"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown compression",
+			config:  gcs.Rolling{MaxRecords: 10, Compression: "zstd"},
+			wantErr: true,
+		},
+		{
+			name:    "delimiter",
+			config:  gcs.Rolling{MaxRecords: 10, CSV: gcs.CSV{Delimiter: ";;"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := gcs.ValidateRolling(&tt.config)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, tt.config, cmpopts.IgnoreUnexported(gcs.Rolling{})); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestRollingNDJSON(t *testing.T) {
+	w, objects := newRollingWriter(t, gcs.Rolling{Prefix: "jr/", MaxRecords: 2})
+
+	records := []string{
+		"{\n  \"id\": 1\n}",
+		`{"id": 2}`,
+		`{"id":3}`,
+	}
+	names := make([]string, 0)
+	for _, r := range records {
+		_, name, err := w.Write([]byte(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	if _, _, err := w.Write([]byte("not json")); err == nil {
+		t.Errorf("expected an error for an invalid JSON record")
+	}
+
+	if diff := cmp.Diff([]bool{true, false}, objects.closed()); diff != "" {
+		t.Errorf("closed: mismatch (-want +got):\n%s", diff)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{\"id\":1}\n{\"id\":2}\n",
+		"{\"id\":3}\n",
+	}
+	if diff := cmp.Diff(want, objects.contents()); diff != "" {
+		t.Errorf("contents: mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]bool{true, true}, objects.closed()); diff != "" {
+		t.Errorf("closed: mismatch (-want +got):\n%s", diff)
+	}
+
+	name := regexp.MustCompile(`^jr/\d{8}T\d{6}Z-00000[12]\.ndjson$`)
+	for _, n := range names {
+		if !name.MatchString(n) {
+			t.Errorf("unexpected object name %s", n)
+		}
+	}
+	if names[0] != names[1] || names[1] == names[2] {
+		t.Errorf("unexpected rolling %v", names)
+	}
+}
+
+func TestRollingMaxBytes(t *testing.T) {
+	// every record is 9 bytes with the newline
+	w, objects := newRollingWriter(t, gcs.Rolling{MaxBytes: 18})
+
+	for i := 0; i < 5; i++ {
+		b, _, err := w.Write([]byte(`{"id":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(9, b); diff != "" {
+			t.Errorf("bytes: mismatch (-want +got):\n%s", diff)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"{\"id\":1}\n{\"id\":1}\n",
+		"{\"id\":1}\n{\"id\":1}\n",
+		"{\"id\":1}\n",
+	}
+	if diff := cmp.Diff(want, objects.contents()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRollingCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		config gcs.CSV
+		want   []string
+	}{
+		{
+			name:   "inferred columns with header",
+			config: gcs.CSV{Header: true},
+			want: []string{
+				"id,name,tags\n1,a,\"[\"\"x\"\"]\"\n2,,\n",
+				"id,name,tags\n3,c,\n",
+			},
+		},
+		{
+			name:   "explicit columns and delimiter",
+			config: gcs.CSV{Columns: []string{"name", "id"}, Delimiter: ";"},
+			want: []string{
+				"a;1\n;2\n",
+				"c;3\n",
+			},
+		},
+	}
+
+	records := []string{
+		`{"id":1,"name":"a","tags":["x"]}`,
+		`{"id":2,"other":true}`,
+		`{"id":3,"name":"c"}`,
+	}
+
+	for _, tt := range tests {
+		w, objects := newRollingWriter(t, gcs.Rolling{MaxRecords: 2, Format: gcs.CSVFormat, CSV: tt.config})
+		for _, r := range records {
+			if _, _, err := w.Write([]byte(r)); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if diff := cmp.Diff(tt.want, objects.contents()); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestRollingGzip(t *testing.T) {
+	w, objects := newRollingWriter(t, gcs.Rolling{MaxRecords: 10, Compression: gcs.GzipCompression})
+
+	_, name, err := w.Write([]byte(`{"id":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !regexp.MustCompile(`\.ndjson\.gz$`).MatchString(name) {
+		t.Errorf("unexpected object name %s", name)
+	}
+
+	r, err := gzip.NewReader(bytes.NewBufferString(objects.contents()[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("{\"id\":1}\n", string(content)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRollingMaxAge(t *testing.T) {
+	w, objects := newRollingWriter(t, gcs.Rolling{MaxAge: "10ms"})
+
+	if _, _, err := w.Write([]byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if objects.closed()[0] {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !objects.closed()[0] {
+		t.Fatalf("object not finalized on age")
+	}
+
+	if _, _, err := w.Write([]byte(`{"id":2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"{\"id\":1}\n", "{\"id\":2}\n"}, objects.contents()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRollingMaxAgeError(t *testing.T) {
+	w, objects := newRollingWriter(t, gcs.Rolling{MaxAge: "10ms"})
+	objects.closeErr = errors.New("upload failed")
+
+	if _, _, err := w.Write([]byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if objects.closed()[0] {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !objects.closed()[0] {
+		t.Fatalf("object not finalized on age")
+	}
+
+	objects.mu.Lock()
+	objects.closeErr = nil
+	objects.mu.Unlock()
+
+	// the failed upload is reported by the next write, which still writes its record
+	if _, _, err := w.Write([]byte(`{"id":2}`)); err == nil {
+		t.Errorf("expected the upload error")
+	}
+	if _, _, err := w.Write([]byte(`{"id":3}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"{\"id\":1}\n", "{\"id\":2}\n{\"id\":3}\n"}, objects.contents()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
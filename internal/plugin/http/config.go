@@ -33,11 +33,17 @@ const (
 	BearerAuth AuthType = "bearer"
 	APIKeyAuth AuthType = "api_key"
 	DigestAuth AuthType = "digest"
+	OAuth2Auth AuthType = "oauth2"
 
-	POST Method = "POST"
-	PUT  Method = "PUT"
+	POST   Method = "POST"
+	PUT    Method = "PUT"
+	PATCH  Method = "PATCH"
+	DELETE Method = "DELETE"
+	GET    Method = "GET"
 )
 
+// Endpoint URL can be a Go template rendered for each record,
+// e.g. https://jr.io/users/{{.key}}
 type Endpoint struct {
 	URL     string `json:"url"`
 	Method  Method `json:"method"`
@@ -45,6 +51,12 @@ type Endpoint struct {
 	timeout time.Duration
 }
 
+// Body template wraps the record into an envelope, e.g. {"data": {{.raw}}},
+// when empty the record is sent as is, except for GET and DELETE requests
+type Body struct {
+	Template string `json:"template"`
+}
+
 type Session struct {
 	UseCookieJar bool `json:"use_cookie_jar"`
 }
@@ -55,6 +67,18 @@ type ErrorHandling struct {
 }
 
 type Headers map[string]string
+
+// RecordHeaders maps the jr record headers and key onto the request headers.
+// When Forward is set all the record headers are sent, or only the ones in
+// Allow if not empty, their names prefixed with Prefix.
+// KeyHeader is the header holding the record key, e.g. Idempotency-Key
+type RecordHeaders struct {
+	Forward   bool     `json:"forward"`
+	Allow     []string `json:"allow"`
+	Prefix    string   `json:"prefix"`
+	KeyHeader string   `json:"key_header"`
+}
+
 type TLS struct {
 	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
 	CertFile           string `json:"cert_file"`
@@ -71,6 +95,18 @@ type Bearer struct {
 	Token string `json:"token"`
 }
 
+// OAuth2 configures the client credentials flow, tokens are cached and
+// refreshed ExpiryDelta before they expire or when a request gets a 401
+type OAuth2 struct {
+	TokenURL       string            `json:"token_url"`
+	ClientID       string            `json:"client_id"`
+	ClientSecret   string            `json:"client_secret"`
+	Scopes         []string          `json:"scopes"`
+	EndpointParams map[string]string `json:"endpoint_params"`
+	ExpiryDelta    string            `json:"expiry_delta"`
+	expiryDelta    time.Duration
+}
+
 type Basic struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
@@ -81,13 +117,16 @@ type Authentication struct {
 	Digest Basic    `json:"digest"`
 	Bearer Bearer   `json:"bearer"`
 	APIKey APIKey   `json:"api_key"`
+	OAuth2 OAuth2   `json:"oauth2"`
 }
 
 type Config struct {
 	Endpoint       Endpoint       `json:"endpoint"`
+	Body           Body           `json:"body"`
 	Session        Session        `json:"session"`
 	ErrorHandling  ErrorHandling  `json:"error_handling"`
 	Headers        Headers        `json:"headers"`
+	RecordHeaders  RecordHeaders  `json:"record_headers"`
 	TLS            TLS            `json:"tls"`
 	Authentication Authentication `json:"authentication"`
 }
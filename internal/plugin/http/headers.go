@@ -0,0 +1,55 @@
+//go:build http
+// +build http
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"slices"
+	"strings"
+)
+
+// hasKey reports whether the record has a key, jr sends "null" for records without one
+func hasKey(k []byte) bool {
+	return len(k) > 0 && strings.ToLower(string(k)) != "null"
+}
+
+// recordHeaders returns the request headers derived from the record key and headers
+func (p *Plugin) recordHeaders(k []byte, headers map[string]string) map[string]string {
+	config := p.configuration.RecordHeaders
+	result := make(map[string]string)
+
+	if config.Forward {
+		for name, value := range headers {
+			if len(config.Allow) > 0 && !slices.Contains(config.Allow, name) {
+				continue
+			}
+			result[config.Prefix+name] = value
+		}
+	}
+
+	if config.KeyHeader != "" && hasKey(k) {
+		result[config.KeyHeader] = string(k)
+	}
+
+	return result
+}
@@ -0,0 +1,123 @@
+//go:build http
+// +build http
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+const DefaultExpiryDelta = 30 * time.Second
+
+func validateOAuth2(config *OAuth2) error {
+	if config.TokenURL == "" {
+		return fmt.Errorf("OAuth2 token URL is mandatory")
+	}
+	if config.ClientID == "" {
+		return fmt.Errorf("OAuth2 client ID is mandatory")
+	}
+
+	config.expiryDelta = DefaultExpiryDelta
+	if config.ExpiryDelta != "" {
+		d, err := time.ParseDuration(config.ExpiryDelta)
+		if err != nil {
+			return err
+		}
+		config.expiryDelta = d
+	}
+
+	return nil
+}
+
+// tokenCache caches the client credentials token, fetching a new one when
+// it is about to expire or has been invalidated
+type tokenCache struct {
+	mu sync.Mutex
+
+	config      clientcredentials.Config
+	client      *http.Client
+	expiryDelta time.Duration
+	token       *oauth2.Token
+}
+
+func newTokenCache(config OAuth2, client *http.Client) *tokenCache {
+	params := make(url.Values, len(config.EndpointParams))
+	for k, v := range config.EndpointParams {
+		params.Set(k, v)
+	}
+
+	return &tokenCache{
+		config: clientcredentials.Config{
+			ClientID:       config.ClientID,
+			ClientSecret:   config.ClientSecret,
+			TokenURL:       config.TokenURL,
+			Scopes:         config.Scopes,
+			EndpointParams: params,
+		},
+		client:      client,
+		expiryDelta: config.expiryDelta,
+	}
+}
+
+// Token returns the cached token, refreshing it if needed
+func (c *tokenCache) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != nil && c.valid() {
+		return c.token.AccessToken, nil
+	}
+
+	// the token endpoint shares the TLS settings of the plugin client
+	token, err := c.config.Token(context.WithValue(ctx, oauth2.HTTPClient, c.client))
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	return token.AccessToken, nil
+}
+
+// Invalidate discards the cached token, e.g. after a 401
+func (c *tokenCache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = nil
+}
+
+func (c *tokenCache) valid() bool {
+	if c.token.AccessToken == "" {
+		return false
+	}
+	if c.token.Expiry.IsZero() {
+		return true
+	}
+	return time.Now().Add(c.expiryDelta).Before(c.token.Expiry)
+}
@@ -31,6 +31,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	"text/template"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -52,6 +53,10 @@ type Plugin struct {
 	certificate tls.Certificate
 	client      *resty.Client
 	cookiejar   http.CookieJar
+
+	urlTemplate  *template.Template
+	bodyTemplate *template.Template
+	tokens       *tokenCache
 }
 
 func (p *Plugin) Init(_ context.Context, cfgBytes []byte) error {
@@ -133,6 +138,11 @@ func (p *Plugin) InitializeFromConfig(config Config) error {
 	case DigestAuth:
 		p.client.SetDigestAuth(p.configuration.Authentication.Digest.Username,
 			p.configuration.Authentication.Digest.Password)
+	case OAuth2Auth:
+		if err := validateOAuth2(&p.configuration.Authentication.OAuth2); err != nil {
+			return err
+		}
+		p.tokens = newTokenCache(p.configuration.Authentication.OAuth2, p.client.GetClient())
 	default:
 
 	}
@@ -141,6 +151,21 @@ func (p *Plugin) InitializeFromConfig(config Config) error {
 		p.configuration.Endpoint.Method = POST
 	}
 
+	switch p.configuration.Endpoint.Method {
+	case POST, PUT, PATCH, DELETE, GET:
+	default:
+		return fmt.Errorf("Unsupported method: %s", p.configuration.Endpoint.Method)
+	}
+
+	p.urlTemplate, err = parseTemplate("url", p.configuration.Endpoint.URL)
+	if err != nil {
+		return err
+	}
+	p.bodyTemplate, err = parseTemplate("body", p.configuration.Body.Template)
+	if err != nil {
+		return err
+	}
+
 	return nil
 
 }
@@ -149,21 +174,51 @@ func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.P
 
 	var err error
 
-	// creating request
-	req := p.client.R().
-		SetBody(v)
+	method := p.configuration.Endpoint.Method
+	endpoint := p.configuration.Endpoint.URL
+	payload := v
 
-	var resp *resty.Response
+	if p.urlTemplate != nil || p.bodyTemplate != nil {
+		data := templateData(k, v, headers)
 
-	switch p.configuration.Endpoint.Method {
-	case POST:
-		resp, err = req.Post(p.configuration.Endpoint.URL)
-	case PUT:
-		resp, err = req.Put(p.configuration.Endpoint.URL)
-	default:
-		resp, err = req.Post(p.configuration.Endpoint.URL)
+		if p.urlTemplate != nil {
+			endpoint, err = render(p.urlTemplate, data)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if p.bodyTemplate != nil {
+			rendered, err := render(p.bodyTemplate, data)
+			if err != nil {
+				return nil, err
+			}
+			payload = []byte(rendered)
+		}
 	}
 
+	send := func() (*resty.Response, error) {
+		// creating request
+		req := p.client.R().
+			SetHeaders(p.recordHeaders(k, headers))
+		if p.bodyTemplate != nil || (method != GET && method != DELETE) {
+			req.SetBody(payload)
+		}
+		if p.tokens != nil {
+			token, err := p.tokens.Token(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			req.SetAuthToken(token)
+		}
+		return req.Execute(string(method), endpoint)
+	}
+
+	resp, err := send()
+	if err == nil && p.tokens != nil && resp.StatusCode() == http.StatusUnauthorized {
+		// the token may have been revoked or expired early, retry once with a new one
+		p.tokens.Invalidate()
+		resp, err = send()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +41,7 @@ var defaultBody = []byte("{\"property\": \"value\"}")
 type mockResponder struct {
 	name          string
 	t             *testing.T
+	expectBody    []byte
 	expectHeaders map[string]string
 	status        int
 	basic         string
@@ -50,12 +51,16 @@ type mockResponder struct {
 
 func (m *mockResponder) serveHTTP(req *http.Request) (*http.Response, error) {
 
-	body, err := io.ReadAll(req.Body)
-	defer req.Body.Close()
-	if err != nil {
-		m.t.Errorf("%s: cannot read request body", m.name)
+	body := []byte{}
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		defer req.Body.Close()
+		if err != nil {
+			m.t.Errorf("%s: cannot read request body", m.name)
+		}
 	}
-	if diff := cmp.Diff(defaultBody, body); diff != "" {
+	if diff := cmp.Diff(m.expectBody, body); diff != "" {
 		m.t.Errorf("%s: mismatch challenge (-want +got):\n%s", m.name, diff)
 	}
 
@@ -108,6 +113,10 @@ func TestProducer(t *testing.T) {
 	testCases := []struct {
 		name    string
 		config  phttp.Config
+		url     string
+		body    []byte
+		key     string
+		record  map[string]string
 		headers map[string]string
 		apiKey  string
 		bearer  string
@@ -134,6 +143,44 @@ func TestProducer(t *testing.T) {
 			},
 			status: http.StatusOK,
 		},
+		{
+			name: "test_templated_url_GET",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl + "/users/{{.key}}?property={{queryescape .value.property}}",
+					Method: phttp.GET,
+				},
+			},
+			url:    fakeUrl + "/users/key?property=value",
+			body:   []byte{},
+			status: http.StatusOK,
+		},
+		{
+			name: "test_templated_url_DELETE",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl + "/users/{{pathescape .key}}",
+					Method: phttp.DELETE,
+				},
+			},
+			url:    fakeUrl + "/users/key",
+			body:   []byte{},
+			status: http.StatusOK,
+		},
+		{
+			name: "test_body_envelope_PATCH",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl,
+					Method: phttp.PATCH,
+				},
+				Body: phttp.Body{
+					Template: `{"id": {{json .key}}, "data": {{.raw}}}`,
+				},
+			},
+			body:   []byte(`{"id": "key", "data": {"property": "value"}}`),
+			status: http.StatusOK,
+		},
 		{
 			name: "test_ignore_status_code",
 			config: phttp.Config{
@@ -178,6 +225,72 @@ func TestProducer(t *testing.T) {
 				"test-jrheader02": "value02",
 			},
 		},
+		{
+			name: "test_with_record_headers",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl,
+					Method: phttp.POST,
+				},
+				RecordHeaders: phttp.RecordHeaders{
+					Forward: true,
+					Prefix:  "Test-Jr-",
+				},
+			},
+			record: map[string]string{
+				"source": "jr",
+				"trace":  "t1",
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"test-jr-source": "jr",
+				"test-jr-trace":  "t1",
+			},
+		},
+		{
+			name: "test_with_allowed_record_headers_and_key",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl,
+					Method: phttp.POST,
+				},
+				RecordHeaders: phttp.RecordHeaders{
+					Forward:   true,
+					Allow:     []string{"Test-Jrsource"},
+					KeyHeader: "Test-Jrkey",
+				},
+			},
+			record: map[string]string{
+				"Test-Jrsource": "jr",
+				"Test-Jrtrace":  "t1",
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"test-jrsource": "jr",
+				"test-jrkey":    "key",
+			},
+		},
+		{
+			name: "test_with_null_key",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl,
+					Method: phttp.POST,
+				},
+				RecordHeaders: phttp.RecordHeaders{
+					Forward:   true,
+					KeyHeader: "Test-Jrkey",
+				},
+			},
+			key: "null",
+			record: map[string]string{
+				"Test-Jrsource": "jr",
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"test-jrsource": "jr",
+			},
+		},
 		{
 			name: "test_with_basic",
 			config: phttp.Config{
@@ -236,13 +349,25 @@ func TestProducer(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			pl := phttp.Plugin{}
-			pl.InitializeFromConfig(tc.config)
+			if err := pl.InitializeFromConfig(tc.config); err != nil {
+				t.Fatal(err)
+			}
 			httpmock.ActivateNonDefault(pl.GetClient().GetClient())
 			httpmock.Reset()
 
+			url := tc.url
+			if url == "" {
+				url = fakeUrl
+			}
+			body := tc.body
+			if body == nil {
+				body = defaultBody
+			}
+
 			mr := &mockResponder{
 				name:          tc.name,
 				t:             t,
+				expectBody:    body,
 				expectHeaders: tc.headers,
 				status:        tc.status,
 				basic:         tc.basic,
@@ -250,15 +375,171 @@ func TestProducer(t *testing.T) {
 				apikey:        tc.apiKey,
 			}
 			httpmock.RegisterResponder(string(tc.config.Endpoint.Method),
-				fakeUrl,
+				url,
 				mr.serveHTTP)
 
-			_, err := pl.Produce([]byte("key"), defaultBody, nil)
+			key := tc.key
+			if key == "" {
+				key = "key"
+			}
+
+			_, err := pl.Produce([]byte(key), defaultBody, tc.record)
 			if err != nil {
 				t.Error(err)
 			}
+			if diff := cmp.Diff(1, httpmock.GetTotalCallCount()); diff != "" {
+				t.Errorf("%s: mismatch calls (-want +got):\n%s", tc.name, diff)
+			}
 			httpmock.DeactivateAndReset()
 		})
 	}
 
 }
+
+func TestTemplateMissingField(t *testing.T) {
+	fakeUrl := "https://jr.io"
+
+	testCases := []struct {
+		name   string
+		config phttp.Config
+		key    string
+		value  []byte
+	}{
+		{
+			name: "test_url_missing_field",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl + "/users/{{.value.id}}",
+					Method: phttp.GET,
+				},
+			},
+			value: defaultBody,
+		},
+		{
+			name: "test_url_value_not_an_object",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl + "/users/{{.value.id}}",
+					Method: phttp.GET,
+				},
+			},
+			value: []byte("not json"),
+		},
+		{
+			name: "test_url_null_key",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl + "/users/{{.key}}",
+					Method: phttp.GET,
+				},
+			},
+			key:   "null",
+			value: defaultBody,
+		},
+		{
+			name: "test_body_missing_field",
+			config: phttp.Config{
+				Endpoint: phttp.Endpoint{
+					URL:    fakeUrl,
+					Method: phttp.POST,
+				},
+				Body: phttp.Body{
+					Template: `{"id": {{json .value.id}}}`,
+				},
+			},
+			value: defaultBody,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pl := phttp.Plugin{}
+			if err := pl.InitializeFromConfig(tc.config); err != nil {
+				t.Fatal(err)
+			}
+			httpmock.ActivateNonDefault(pl.GetClient().GetClient())
+			defer httpmock.DeactivateAndReset()
+
+			key := tc.key
+			if key == "" {
+				key = "key"
+			}
+			if _, err := pl.Produce([]byte(key), tc.value, nil); err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			if diff := cmp.Diff(0, httpmock.GetTotalCallCount()); diff != "" {
+				t.Errorf("%s: mismatch calls (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	fakeUrl := "https://jr.io"
+	tokenUrl := "https://auth.jr.io/token"
+
+	pl := phttp.Plugin{}
+	err := pl.InitializeFromConfig(phttp.Config{
+		Endpoint: phttp.Endpoint{
+			URL:    fakeUrl,
+			Method: phttp.POST,
+		},
+		Authentication: phttp.Authentication{
+			Type: phttp.OAuth2Auth,
+			OAuth2: phttp.OAuth2{
+				TokenURL:     tokenUrl,
+				ClientID:     "client",
+				ClientSecret: "secret",
+				Scopes:       []string{"write"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpmock.ActivateNonDefault(pl.GetClient().GetClient())
+	defer httpmock.DeactivateAndReset()
+
+	tokens := 0
+	httpmock.RegisterResponder(http.MethodPost, tokenUrl,
+		func(req *http.Request) (*http.Response, error) {
+			if err := req.ParseForm(); err != nil {
+				t.Error(err)
+			}
+			if diff := cmp.Diff("client_credentials", req.PostForm.Get("grant_type")); diff != "" {
+				t.Errorf("mismatch grant type (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff("write", req.PostForm.Get("scope")); diff != "" {
+				t.Errorf("mismatch scope (-want +got):\n%s", diff)
+			}
+			tokens++
+			return httpmock.NewJsonResponse(http.StatusOK, map[string]interface{}{
+				"access_token": fmt.Sprintf("token%d", tokens),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		})
+
+	// the first token is rejected once to check the refresh on 401
+	httpmock.RegisterResponder(http.MethodPost, fakeUrl,
+		func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") == "Bearer token1" && tokens == 1 {
+				return httpmock.NewStringResponse(http.StatusUnauthorized, ""), nil
+			}
+			if diff := cmp.Diff(fmt.Sprintf("Bearer token%d", tokens), req.Header.Get("Authorization")); diff != "" {
+				t.Errorf("mismatch token (-want +got):\n%s", diff)
+			}
+			return httpmock.NewStringResponse(http.StatusOK, ""), nil
+		})
+
+	for i := 0; i < 3; i++ {
+		if _, err := pl.Produce([]byte("key"), defaultBody, nil); err != nil {
+			t.Error(err)
+		}
+	}
+
+	// one token rejected, one refreshed and then cached
+	if diff := cmp.Diff(2, tokens); diff != "" {
+		t.Errorf("mismatch token requests (-want +got):\n%s", diff)
+	}
+}
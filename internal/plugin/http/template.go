@@ -0,0 +1,80 @@
+//go:build http
+// +build http
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"text/template"
+)
+
+var templateFuncs = template.FuncMap{
+	"pathescape":  url.PathEscape,
+	"queryescape": url.QueryEscape,
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseTemplate parses the text as a template, returning nil when the text
+// holds no action so that it can be used verbatim.
+// Fields missing from the record fail the rendering, so that no request is
+// sent to a URL such as /users/<no value>
+func parseTemplate(name string, text string) (*template.Template, error) {
+	if !strings.Contains(text, "{{") {
+		return nil, nil
+	}
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// templateData is the data available to the URL and body templates:
+// key and raw are the record key and value as strings, value is the decoded
+// JSON value and headers the jr record headers. key is missing for records
+// without a key, so that templates using it fail
+func templateData(k []byte, v []byte, headers map[string]string) map[string]interface{} {
+	data := map[string]interface{}{
+		"raw":     string(v),
+		"headers": headers,
+	}
+	if hasKey(k) {
+		data["key"] = string(k)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(v, &value); err == nil {
+		data["value"] = value
+	}
+	return data
+}
+
+func render(tmpl *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
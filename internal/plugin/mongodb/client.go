@@ -0,0 +1,153 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	X509Mechanism = "MONGODB-X509"
+
+	defaultPingTimeout = 10 * time.Second
+)
+
+// buildClientOptions builds the driver options from the configuration.
+// Options explicitly set in the configuration override the ones in the URI.
+func buildClientOptions(config Config) (*options.ClientOptions, error) {
+	clientOptions := options.Client().ApplyURI(config.MongoURI)
+
+	if config.Username != "" || config.Password != "" || config.Mechanism != "" || config.AuthSource != "" {
+		// SetAuth replaces the whole credential, start from the one in the URI
+		var credential options.Credential
+		if clientOptions.Auth != nil {
+			credential = *clientOptions.Auth
+		}
+		if config.Mechanism != "" {
+			credential.AuthMechanism = config.Mechanism
+		}
+		if config.AuthSource != "" {
+			credential.AuthSource = config.AuthSource
+		}
+		if config.Username != "" {
+			credential.Username = config.Username
+		}
+		if config.Password != "" {
+			credential.Password = config.Password
+			credential.PasswordSet = true
+		}
+		if config.Mechanism == X509Mechanism {
+			if config.TLS.CertFile == "" {
+				return nil, fmt.Errorf("CertFile is mandatory with %s authentication", X509Mechanism)
+			}
+			// the user is taken from the client certificate
+			credential.Password = ""
+			credential.PasswordSet = false
+		}
+		if credential.Username == "" && credential.AuthMechanism == "" {
+			return nil, fmt.Errorf("AuthSource requires a username or an auth mechanism")
+		}
+		clientOptions.SetAuth(credential)
+	}
+
+	if config.TLS.Enabled || config.TLS.CertFile != "" || config.TLS.RootCAFile != "" {
+		tlsConfig, err := buildTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
+	if config.Pool.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(config.Pool.MaxPoolSize)
+	}
+	if config.Pool.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(config.Pool.MinPoolSize)
+	}
+
+	if config.Timeouts.Connect != "" {
+		timeout, err := time.ParseDuration(config.Timeouts.Connect)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetConnectTimeout(timeout)
+	}
+	if config.Timeouts.ServerSelection != "" {
+		timeout, err := time.ParseDuration(config.Timeouts.ServerSelection)
+		if err != nil {
+			return nil, err
+		}
+		clientOptions.SetServerSelectionTimeout(timeout)
+	}
+
+	return clientOptions, clientOptions.Validate()
+}
+
+func buildTLSConfig(config TLS) (*tls.Config, error) {
+	// #nosec G402
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if config.RootCAFile != "" {
+		pem, err := os.ReadFile(config.RootCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.RootCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if config.CertFile != "" {
+		// Mongo client certificates usually bundle the key in the same PEM file
+		keyFile := config.KeyFile
+		if keyFile == "" {
+			keyFile = config.CertFile
+		}
+		certificate, err := tls.LoadX509KeyPair(config.CertFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func parsePingTimeout(config Timeouts) (time.Duration, error) {
+	if config.Ping == "" {
+		return defaultPingTimeout, nil
+	}
+	return time.ParseDuration(config.Ping)
+}
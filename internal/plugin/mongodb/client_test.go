@@ -0,0 +1,152 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/mongodb"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBuildClientOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      mongodb.Config
+		wantAuth    *options.Credential
+		wantPool    uint64
+		wantConnect time.Duration
+		wantErr     bool
+	}{
+		{
+			name:   "no auth",
+			config: mongodb.Config{MongoURI: "mongodb://localhost:27017"},
+		},
+		{
+			name:     "uri credential",
+			config:   mongodb.Config{MongoURI: "mongodb://u:p@localhost:27017"},
+			wantAuth: &options.Credential{AuthSource: "admin", Username: "u", Password: "p", PasswordSet: true},
+		},
+		{
+			name: "username and password",
+			config: mongodb.Config{
+				MongoURI: "mongodb://localhost:27017",
+				Username: "jr",
+				Password: "secret",
+			},
+			wantAuth: &options.Credential{Username: "jr", Password: "secret", PasswordSet: true},
+		},
+		{
+			name: "mechanism keeps the uri credential",
+			config: mongodb.Config{
+				MongoURI:  "mongodb://u:p@localhost:27017",
+				Mechanism: "SCRAM-SHA-256",
+			},
+			wantAuth: &options.Credential{AuthMechanism: "SCRAM-SHA-256", AuthSource: "admin", Username: "u", Password: "p", PasswordSet: true},
+		},
+		{
+			name: "auth source keeps the uri credential",
+			config: mongodb.Config{
+				MongoURI:   "mongodb://u:p@localhost:27017/?authMechanism=SCRAM-SHA-1",
+				AuthSource: "admin",
+			},
+			wantAuth: &options.Credential{AuthMechanism: "SCRAM-SHA-1", AuthSource: "admin", Username: "u", Password: "p", PasswordSet: true},
+		},
+		{
+			name: "password overrides the uri one",
+			config: mongodb.Config{
+				MongoURI: "mongodb://u:p@localhost:27017",
+				Password: "other",
+			},
+			wantAuth: &options.Credential{AuthSource: "admin", Username: "u", Password: "other", PasswordSet: true},
+		},
+		{
+			name: "auth source without credential",
+			config: mongodb.Config{
+				MongoURI:   "mongodb://localhost:27017",
+				AuthSource: "admin",
+			},
+			wantErr: true,
+		},
+		{
+			name: "x509 without certificate",
+			config: mongodb.Config{
+				MongoURI:  "mongodb://localhost:27017",
+				Mechanism: mongodb.X509Mechanism,
+			},
+			wantErr: true,
+		},
+		{
+			name: "pool and timeouts",
+			config: mongodb.Config{
+				MongoURI: "mongodb://localhost:27017",
+				Pool:     mongodb.Pool{MaxPoolSize: 20},
+				Timeouts: mongodb.Timeouts{Connect: "3s"},
+			},
+			wantPool:    20,
+			wantConnect: 3 * time.Second,
+		},
+		{
+			name: "invalid timeout",
+			config: mongodb.Config{
+				MongoURI: "mongodb://localhost:27017",
+				Timeouts: mongodb.Timeouts{ServerSelection: "soon"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := mongodb.BuildClientOptions(tt.config)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		if tt.wantAuth != nil && got.Auth != nil {
+			// the driver keeps the raw URI properties, only the credential is compared
+			got.Auth.AuthMechanismProperties = nil
+		}
+		if diff := cmp.Diff(tt.wantAuth, got.Auth); diff != "" {
+			t.Errorf("%s: mismatch auth (-want +got):\n%s", tt.name, diff)
+		}
+		if tt.wantPool > 0 {
+			if diff := cmp.Diff(tt.wantPool, *got.MaxPoolSize); diff != "" {
+				t.Errorf("%s: mismatch pool (-want +got):\n%s", tt.name, diff)
+			}
+		}
+		if tt.wantConnect > 0 {
+			if diff := cmp.Diff(tt.wantConnect, *got.ConnectTimeout); diff != "" {
+				t.Errorf("%s: mismatch connect timeout (-want +got):\n%s", tt.name, diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type IDStrategy string
+
+const (
+	KeyID      IDStrategy = "key"
+	FieldID    IDStrategy = "field"
+	GenerateID IDStrategy = "generate"
+)
+
+type WriteMode string
+
+const (
+	InsertMode  WriteMode = "insert"
+	UpsertMode  WriteMode = "upsert"
+	ReplaceMode WriteMode = "replace"
+)
+
+type Batch struct {
+	Enabled   bool `json:"enabled"`
+	Size      int  `json:"size"`
+	Unordered bool `json:"unordered"`
+}
+
+// Acknowledgement is the w option of the write concern,
+// either a number of nodes or a tag such as "majority"
+type Acknowledgement string
+
+func (a *Acknowledgement) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*a = Acknowledgement(strconv.Itoa(n))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*a = Acknowledgement(s)
+	return nil
+}
+
+type WriteConcern struct {
+	W        Acknowledgement `json:"w"`
+	J        bool            `json:"j"`
+	WTimeout string          `json:"wtimeout"`
+}
+
+type TLS struct {
+	Enabled            bool   `json:"enabled"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	CertFile           string `json:"cert_file"`
+	KeyFile            string `json:"key_file"`
+	RootCAFile         string `json:"root_ca_file"`
+}
+
+type Pool struct {
+	MaxPoolSize uint64 `json:"max_pool_size"`
+	MinPoolSize uint64 `json:"min_pool_size"`
+}
+
+type Timeouts struct {
+	Connect         string `json:"connect"`
+	ServerSelection string `json:"server_selection"`
+	Ping            string `json:"ping"`
+}
+
+type Config struct {
+	MongoURI   string     `json:"mongo_uri"`
+	Username   string     `json:"username"`
+	Password   string     `json:"password"`
+	AuthSource string     `json:"auth_source"`
+	Mechanism  string     `json:"auth_mechanism"`
+	Database   string     `json:"database"`
+	Collection string     `json:"collection"`
+	IDStrategy IDStrategy `json:"id_strategy"`
+	IDField    string     `json:"id_field"`
+
+	WriteMode   WriteMode `json:"write_mode"`
+	FilterField string    `json:"filter_field"`
+	Batch       Batch     `json:"batch"`
+
+	WriteConcern WriteConcern `json:"write_concern"`
+	ExtendedJSON bool         `json:"extended_json"`
+	DateFields   []string     `json:"date_fields"`
+
+	TLS      TLS      `json:"tls"`
+	Pool     Pool     `json:"pool"`
+	Timeouts Timeouts `json:"timeouts"`
+}
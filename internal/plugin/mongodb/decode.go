@@ -0,0 +1,127 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// Decode parses a jr value into a document.
+// With extended set, Extended JSON such as $date, $oid and $numberDecimal
+// is decoded into the matching BSON types.
+func Decode(v []byte, extended bool) (map[string]interface{}, error) {
+	var doc map[string]interface{}
+	if extended {
+		if err := bson.UnmarshalExtJSON(v, false, &doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+	if err := json.Unmarshal(v, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// CoerceDates converts the ISO-8601 strings found at the given
+// dot separated paths into time.Time
+func CoerceDates(doc map[string]interface{}, fields []string) error {
+	for _, field := range fields {
+		if err := coerceDate(doc, strings.Split(field, ".")); err != nil {
+			return fmt.Errorf("date field %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func coerceDate(doc map[string]interface{}, path []string) error {
+	value, ok := doc[path[0]]
+	if !ok || value == nil {
+		return nil
+	}
+
+	if len(path) > 1 {
+		switch nested := value.(type) {
+		case map[string]interface{}:
+			return coerceDate(nested, path[1:])
+		case bson.M:
+			return coerceDate(nested, path[1:])
+		case bson.D:
+			for i := range nested {
+				if nested[i].Key != path[1] {
+					continue
+				}
+				element := map[string]interface{}{path[1]: nested[i].Value}
+				err := coerceDate(element, path[1:])
+				nested[i].Value = element[path[1]]
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			doc[path[0]] = t
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not an ISO-8601 date", s)
+}
+
+func buildWriteConcern(config WriteConcern) (*writeconcern.WriteConcern, error) {
+	if config.W == "" && !config.J && config.WTimeout == "" {
+		return nil, nil
+	}
+
+	wc := &writeconcern.WriteConcern{}
+	if config.W != "" {
+		if n, err := strconv.Atoi(string(config.W)); err == nil {
+			wc.W = n
+		} else {
+			wc.W = string(config.W)
+		}
+	}
+	if config.J {
+		wc.Journal = &config.J
+	}
+	if config.WTimeout != "" {
+		var err error
+		if wc.WTimeout, err = time.ParseDuration(config.WTimeout); err != nil {
+			return nil, err
+		}
+	}
+	return wc, nil
+}
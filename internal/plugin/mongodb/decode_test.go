@@ -0,0 +1,89 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr-plugins/internal/plugin/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecodeExtendedJSON(t *testing.T) {
+	value := `{"_id":{"$oid":"66d1c2f0e4b0a1b2c3d4e5f6"},"at":{"$date":"2024-08-30T12:00:00Z"},"price":{"$numberDecimal":"10.25"}}`
+
+	doc, err := mongodb.Decode([]byte(value), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["_id"].(primitive.ObjectID); !ok {
+		t.Errorf("_id: unexpected type %T", doc["_id"])
+	}
+	if _, ok := doc["at"].(primitive.DateTime); !ok {
+		t.Errorf("at: unexpected type %T", doc["at"])
+	}
+	if _, ok := doc["price"].(primitive.Decimal128); !ok {
+		t.Errorf("price: unexpected type %T", doc["price"])
+	}
+
+	doc, err = mongodb.Decode([]byte(value), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc["_id"].(map[string]interface{}); !ok {
+		t.Errorf("plain JSON _id: unexpected type %T", doc["_id"])
+	}
+}
+
+func TestCoerceDates(t *testing.T) {
+	want := time.Date(2024, 8, 30, 12, 0, 0, 0, time.UTC)
+
+	doc := map[string]interface{}{
+		"created_at": "2024-08-30T12:00:00Z",
+		"order":      map[string]interface{}{"shipped_at": "2024-08-30T12:00:00Z"},
+		"ext":        bson.D{{Key: "at", Value: "2024-08-30T12:00:00Z"}},
+		"name":       "jr",
+	}
+	err := mongodb.CoerceDates(doc, []string{"created_at", "order.shipped_at", "ext.at", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []interface{}{
+		doc["created_at"],
+		doc["order"].(map[string]interface{})["shipped_at"],
+		doc["ext"].(bson.D)[0].Value,
+	}
+	for i, g := range got {
+		if d, ok := g.(time.Time); !ok || !d.Equal(want) {
+			t.Errorf("date %d: got %v", i, g)
+		}
+	}
+
+	if err := mongodb.CoerceDates(doc, []string{"name"}); err == nil {
+		t.Errorf("expected an error for a non date field")
+	}
+}
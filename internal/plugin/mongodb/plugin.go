@@ -26,23 +26,19 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
 )
 
-type Config struct {
-	MongoURI   string `json:"mongo_uri"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	Database   string `json:"database"`
-	Collection string `json:"collection"`
-}
-
 const (
 	Name = "mongodb"
 )
@@ -52,9 +48,12 @@ func init() {
 }
 
 type Plugin struct {
+	configuration Config
+
 	client     mongo.Client
 	database   string
 	collection string
+	batch      *batchWriter
 }
 
 func (p *Plugin) Init(ctx context.Context, configBytes []byte) error {
@@ -64,14 +63,41 @@ func (p *Plugin) Init(ctx context.Context, configBytes []byte) error {
 		return err
 	}
 
-	clientOptions := options.Client().ApplyURI(config.MongoURI)
-	if config.Username != "" && config.Password != "" {
-		clientOptions.Auth = &options.Credential{
-			Username: config.Username,
-			Password: config.Password,
+	clientOptions, err := buildClientOptions(config)
+	if err != nil {
+		return err
+	}
+
+	pingTimeout, err := parsePingTimeout(config.Timeouts)
+	if err != nil {
+		return err
+	}
+
+	switch config.IDStrategy {
+	case "":
+		config.IDStrategy = KeyID
+	case KeyID, GenerateID:
+	case FieldID:
+		if config.IDField == "" {
+			return fmt.Errorf("IDField is mandatory with the field id strategy")
 		}
+	default:
+		return fmt.Errorf("Unknown id strategy: %s", config.IDStrategy)
 	}
 
+	if err := validateWriteMode(&config); err != nil {
+		return err
+	}
+
+	wc, err := buildWriteConcern(config.WriteConcern)
+	if err != nil {
+		return err
+	}
+	if wc != nil {
+		clientOptions.SetWriteConcern(wc)
+	}
+
+	p.configuration = config
 	p.collection = config.Collection
 	p.database = config.Database
 
@@ -81,7 +107,19 @@ func (p *Plugin) Init(ctx context.Context, configBytes []byte) error {
 		return err
 	}
 
+	// fail early on misconfiguration instead of on the first insert
+	pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := client.Ping(pingCtx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(ctx)
+		return err
+	}
+
 	p.client = *client
+
+	if config.Batch.Enabled {
+		p.batch = newBatchWriter(client.Database(p.database).Collection(p.collection), config.Batch)
+	}
 	return nil
 }
 
@@ -89,31 +127,92 @@ func (p *Plugin) Produce(key []byte, v []byte, headers map[string]string) (*jrpc
 
 	collection := p.client.Database(p.database).Collection(p.collection)
 
-	var dev map[string]interface{}
-	err := json.Unmarshal(v, &dev)
+	dev, err := Decode(v, p.configuration.ExtendedJSON)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(key) == 0 {
-		dev["_id"] = string(key)
+	if err := CoerceDates(dev, p.configuration.DateFields); err != nil {
+		return nil, err
 	}
 
-	resp, err := collection.InsertOne(context.Background(), dev)
-	if err != nil {
+	if err := SetID(dev, key, p.configuration.IDStrategy, p.configuration.IDField); err != nil {
 		return nil, err
 	}
 
+	var message string
+	if p.batch != nil {
+		model, err := BuildModel(dev, p.configuration.WriteMode, p.configuration.FilterField)
+		if err != nil {
+			return nil, err
+		}
+		message, err = p.batch.Add(context.Background(), model)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		message, err = p.write(context.Background(), collection, dev)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &jrpc.ProduceResponse{
 		Bytes:   uint64(len(v)),
-		Message: resp.InsertedID.(string),
+		Message: message,
 	}, nil
 }
 
+// SetID sets the document _id according to the strategy.
+// When no _id is set, Mongo generates an ObjectID.
+func SetID(doc map[string]interface{}, key []byte, strategy IDStrategy, field string) error {
+	switch strategy {
+	case KeyID:
+		// fall back to a generated ObjectID without a key
+		if len(key) > 0 && strings.ToLower(string(key)) != "null" {
+			doc["_id"] = string(key)
+		}
+	case FieldID:
+		id, ok := doc[field]
+		if !ok || id == nil {
+			return fmt.Errorf("id field %s not found in value", field)
+		}
+		doc["_id"] = id
+	case GenerateID:
+		delete(doc, "_id")
+	}
+	return nil
+}
+
+// FormatID stringifies an inserted id of any type
+func FormatID(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case primitive.ObjectID:
+		return v.Hex()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
 func (p *Plugin) Close(ctx context.Context) error {
+	var flushErr error
+	if p.batch != nil {
+		var message string
+		message, flushErr = p.batch.Flush(ctx)
+		if flushErr != nil {
+			log.Warn().Err(flushErr).Msg("Failed to flush pending documents")
+		} else {
+			log.Debug().Str("result", message).Msg("Flushed pending documents")
+		}
+	}
+
 	err := p.client.Disconnect(ctx)
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to close Mongo connection")
 	}
-	return err
+	return errors.Join(flushErr, err)
 }
@@ -0,0 +1,92 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/mongodb"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSetID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		key      string
+		strategy mongodb.IDStrategy
+		field    string
+		wantID   interface{}
+		wantErr  bool
+	}{
+		{name: "key", key: "k1", strategy: mongodb.KeyID, wantID: "k1"},
+		{name: "empty_key", key: "", strategy: mongodb.KeyID},
+		{name: "null_key", key: "null", strategy: mongodb.KeyID},
+		{name: "field", key: "k1", strategy: mongodb.FieldID, field: "code", wantID: float64(42)},
+		{name: "missing_field", strategy: mongodb.FieldID, field: "missing", wantErr: true},
+		{name: "generate", key: "k1", strategy: mongodb.GenerateID},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc := map[string]interface{}{"code": float64(42)}
+			err := mongodb.SetID(doc, []byte(tc.key), tc.strategy, tc.field)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("%s: expected an error", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.wantID, doc["_id"]); diff != "" {
+				t.Errorf("%s: mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
+
+func TestFormatID(t *testing.T) {
+	oid := primitive.NewObjectID()
+
+	testCases := []struct {
+		name string
+		id   interface{}
+		want string
+	}{
+		{name: "string", id: "k1", want: "k1"},
+		{name: "object_id", id: oid, want: oid.Hex()},
+		{name: "number", id: int64(42), want: "42"},
+		{name: "nil", id: nil, want: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, mongodb.FormatID(tc.id)); diff != "" {
+				t.Errorf("%s: mismatch (-want +got):\n%s", tc.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const defaultBatchSize = 1000
+
+func validateWriteMode(config *Config) error {
+	switch config.WriteMode {
+	case "":
+		config.WriteMode = InsertMode
+	case InsertMode, UpsertMode, ReplaceMode:
+	default:
+		return fmt.Errorf("Unknown write mode: %s", config.WriteMode)
+	}
+
+	if config.FilterField == "" {
+		config.FilterField = "_id"
+	}
+
+	if config.Batch.Enabled {
+		if config.Batch.Size == 0 {
+			config.Batch.Size = defaultBatchSize
+		}
+		if config.Batch.Size < 0 {
+			return fmt.Errorf("Batch size must be positive")
+		}
+	}
+
+	return nil
+}
+
+// BuildModel creates the write model for the document.
+// Upserts $set every field but _id, which is only set on insert,
+// replacements match existing documents only.
+func BuildModel(doc map[string]interface{}, mode WriteMode, filterField string) (mongo.WriteModel, error) {
+	if mode == InsertMode {
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	}
+
+	filterValue, ok := doc[filterField]
+	if !ok || filterValue == nil {
+		return nil, fmt.Errorf("filter field %s not found in value", filterField)
+	}
+	filter := bson.M{filterField: filterValue}
+
+	fields := make(bson.M, len(doc))
+	for k, v := range doc {
+		if k != "_id" {
+			fields[k] = v
+		}
+	}
+
+	switch mode {
+	case UpsertMode:
+		// a document holding only _id has no field to set, the server rejects an empty $set
+		update := bson.M{}
+		if len(fields) > 0 {
+			update["$set"] = fields
+		}
+		if id, ok := doc["_id"]; ok {
+			update["$setOnInsert"] = bson.M{"_id": id}
+		}
+		return mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(update).
+			SetUpsert(true), nil
+	case ReplaceMode:
+		return mongo.NewReplaceOneModel().
+			SetFilter(filter).
+			SetReplacement(fields), nil
+	}
+	return nil, fmt.Errorf("unknown write mode: %s", mode)
+}
+
+func (p *Plugin) write(ctx context.Context, collection *mongo.Collection, doc map[string]interface{}) (string, error) {
+	switch p.configuration.WriteMode {
+	case UpsertMode, ReplaceMode:
+		model, err := BuildModel(doc, p.configuration.WriteMode, p.configuration.FilterField)
+		if err != nil {
+			return "", err
+		}
+		resp, err := collection.BulkWrite(ctx, []mongo.WriteModel{model})
+		if err != nil {
+			return "", err
+		}
+		return resultMessage(resp), nil
+	default:
+		resp, err := collection.InsertOne(ctx, doc)
+		if err != nil {
+			return "", err
+		}
+		return FormatID(resp.InsertedID), nil
+	}
+}
+
+func resultMessage(resp *mongo.BulkWriteResult) string {
+	if len(resp.UpsertedIDs) == 1 {
+		for _, id := range resp.UpsertedIDs {
+			return FormatID(id)
+		}
+	}
+	return fmt.Sprintf("inserted %d, matched %d, modified %d, upserted %d",
+		resp.InsertedCount, resp.MatchedCount, resp.ModifiedCount, resp.UpsertedCount)
+}
+
+// bulkWriter is the subset of mongo.Collection used by the batch writer
+type bulkWriter interface {
+	BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error)
+}
+
+// batchWriter groups write models into BulkWrite requests
+type batchWriter struct {
+	mu sync.Mutex
+
+	collection bulkWriter
+	size       int
+	ordered    bool
+	options    *options.BulkWriteOptions
+	models     []mongo.WriteModel
+}
+
+func newBatchWriter(collection bulkWriter, config Batch) *batchWriter {
+	return &batchWriter{
+		collection: collection,
+		size:       config.Size,
+		ordered:    !config.Unordered,
+		options:    options.BulkWrite().SetOrdered(!config.Unordered),
+		models:     make([]mongo.WriteModel, 0, config.Size),
+	}
+}
+
+// Add buffers the model and writes the batch once it is full
+func (b *batchWriter) Add(ctx context.Context, model mongo.WriteModel) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.models = append(b.models, model)
+	if len(b.models) < b.size {
+		return "", nil
+	}
+	return b.flush(ctx)
+}
+
+// Flush writes the buffered models
+func (b *batchWriter) Flush(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flush(ctx)
+}
+
+func (b *batchWriter) flush(ctx context.Context) (string, error) {
+	if len(b.models) == 0 {
+		return "", nil
+	}
+
+	models := b.models
+	b.models = make([]mongo.WriteModel, 0, b.size)
+
+	resp, err := b.collection.BulkWrite(ctx, models, b.options)
+
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		failed := len(bwe.WriteErrors)
+		// ordered writes stop at the first error, the following ones are not executed
+		notExecuted := 0
+		if b.ordered && failed > 0 {
+			notExecuted = len(models) - bwe.WriteErrors[0].Index - 1
+		}
+		log.Warn().Err(err).Int("failed", failed).Int("not_executed", notExecuted).Msg("Bulk write partially failed")
+		if notExecuted > 0 {
+			return "", fmt.Errorf("%d of %d documents failed and %d were not executed: %w", failed, len(models), notExecuted, err)
+		}
+		return "", fmt.Errorf("%d of %d documents failed: %w", failed, len(models), err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return resultMessage(resp), nil
+}
@@ -0,0 +1,187 @@
+//go:build mongodb
+// +build mongodb
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestBuildModel(t *testing.T) {
+	doc := map[string]interface{}{"_id": "k1", "code": "c1", "name": "jr"}
+
+	model, err := mongodb.BuildModel(doc, mongodb.InsertMode, "_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := model.(*mongo.InsertOneModel); !ok {
+		t.Errorf("insert: unexpected model %T", model)
+	}
+
+	model, err = mongodb.BuildModel(doc, mongodb.UpsertMode, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upsert, ok := model.(*mongo.UpdateOneModel)
+	if !ok {
+		t.Fatalf("upsert: unexpected model %T", model)
+	}
+	if diff := cmp.Diff(bson.M{"code": "c1"}, upsert.Filter); diff != "" {
+		t.Errorf("upsert: mismatch filter (-want +got):\n%s", diff)
+	}
+	wantUpdate := bson.M{
+		"$set":         bson.M{"code": "c1", "name": "jr"},
+		"$setOnInsert": bson.M{"_id": "k1"},
+	}
+	if diff := cmp.Diff(wantUpdate, upsert.Update); diff != "" {
+		t.Errorf("upsert: mismatch update (-want +got):\n%s", diff)
+	}
+	if upsert.Upsert == nil || !*upsert.Upsert {
+		t.Errorf("upsert: upsert option not set")
+	}
+
+	model, err = mongodb.BuildModel(doc, mongodb.ReplaceMode, "_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	replace, ok := model.(*mongo.ReplaceOneModel)
+	if !ok {
+		t.Fatalf("replace: unexpected model %T", model)
+	}
+	if diff := cmp.Diff(bson.M{"_id": "k1"}, replace.Filter); diff != "" {
+		t.Errorf("replace: mismatch filter (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(bson.M{"code": "c1", "name": "jr"}, replace.Replacement); diff != "" {
+		t.Errorf("replace: mismatch replacement (-want +got):\n%s", diff)
+	}
+
+	if _, err := mongodb.BuildModel(doc, mongodb.UpsertMode, "missing"); err == nil {
+		t.Errorf("missing filter field: expected an error")
+	}
+
+	model, err = mongodb.BuildModel(map[string]interface{}{"_id": "k2"}, mongodb.UpsertMode, "_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(bson.M{"$setOnInsert": bson.M{"_id": "k2"}}, model.(*mongo.UpdateOneModel).Update); diff != "" {
+		t.Errorf("only _id: mismatch update (-want +got):\n%s", diff)
+	}
+}
+
+// stubCollection fails the writes at the given indexes, stopping at the
+// first one when the bulk write is ordered
+type stubCollection struct {
+	failed []int
+	models int
+}
+
+func (s *stubCollection) BulkWrite(_ context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	s.models += len(models)
+	if len(s.failed) == 0 {
+		return &mongo.BulkWriteResult{InsertedCount: int64(len(models))}, nil
+	}
+
+	failed := s.failed
+	if opts[0].Ordered == nil || *opts[0].Ordered {
+		failed = failed[:1]
+	}
+	bwe := mongo.BulkWriteException{}
+	for _, i := range failed {
+		bwe.WriteErrors = append(bwe.WriteErrors, mongo.BulkWriteError{WriteError: mongo.WriteError{Index: i, Code: 11000, Message: "duplicate key"}})
+	}
+	return &mongo.BulkWriteResult{}, bwe
+}
+
+func TestBatchWriter(t *testing.T) {
+	tests := []struct {
+		name      string
+		batch     mongodb.Batch
+		failed    []int
+		wantErr   string
+		wantFlush string
+	}{
+		{
+			name:      "all written",
+			batch:     mongodb.Batch{Size: 4},
+			wantFlush: "inserted 4, matched 0, modified 0, upserted 0",
+		},
+		{
+			name:    "ordered stops at the first error",
+			batch:   mongodb.Batch{Size: 4},
+			failed:  []int{1, 2},
+			wantErr: "1 of 4 documents failed and 2 were not executed",
+		},
+		{
+			name:    "ordered error on the last document",
+			batch:   mongodb.Batch{Size: 4},
+			failed:  []int{3},
+			wantErr: "1 of 4 documents failed:",
+		},
+		{
+			name:    "unordered",
+			batch:   mongodb.Batch{Size: 4, Unordered: true},
+			failed:  []int{1, 2},
+			wantErr: "2 of 4 documents failed:",
+		},
+	}
+
+	for _, tt := range tests {
+		collection := &stubCollection{failed: tt.failed}
+		w := mongodb.NewBatchWriter(collection, tt.batch)
+
+		var message string
+		var err error
+		for i := 0; i < tt.batch.Size; i++ {
+			message, err = w.Add(context.Background(), mongo.NewInsertOneModel().SetDocument(bson.M{"_id": i}))
+			if i < tt.batch.Size-1 && (err != nil || message != "") {
+				t.Fatalf("%s: unexpected write before the batch is full", tt.name)
+			}
+		}
+
+		if diff := cmp.Diff(tt.batch.Size, collection.models); diff != "" {
+			t.Errorf("%s: mismatch models (-want +got):\n%s", tt.name, diff)
+		}
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if diff := cmp.Diff(tt.wantFlush, message); diff != "" {
+				t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+			}
+			continue
+		}
+		var bwe mongo.BulkWriteException
+		if err == nil || !errors.As(err, &bwe) || !strings.HasPrefix(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+//go:build s3
+// +build s3
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package s3
+
+import "github.com/jrnd-io/jr-plugins/internal/mapping"
+
+type ServerSideEncryption struct {
+	Type     string `json:"type"`
+	KMSKeyID string `json:"kms_key_id"`
+}
+
+type Config struct {
+	Bucket               string               `json:"bucket"`
+	ContentType          string               `json:"content_type"`
+	ContentEncoding      string               `json:"content_encoding"`
+	StorageClass         string               `json:"storage_class"`
+	ServerSideEncryption ServerSideEncryption `json:"server_side_encryption"`
+	Tags                 map[string]string    `json:"tags"`
+	Metadata             mapping.Mapping      `json:"metadata"`
+}
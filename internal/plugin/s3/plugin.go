@@ -27,21 +27,21 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 	"github.com/jrnd-io/jr-plugins/internal/plugin"
 	"github.com/jrnd-io/jrv2/pkg/jrpc"
 )
 
-type Config struct {
-	Bucket string `json:"bucket"`
-}
-
 const (
 	Name = "s3"
 )
@@ -51,8 +51,11 @@ func init() {
 }
 
 type Plugin struct {
-	client *awss3.Client
-	bucket string
+	configuration Config
+
+	client  *awss3.Client
+	bucket  string
+	tagging string
 }
 
 func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
@@ -62,6 +65,10 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 		return err
 	}
 
+	if err := validateConfig(config); err != nil {
+		return err
+	}
+
 	awsConfig, err := awsconfig.LoadDefaultConfig(ctx)
 	if err != nil {
 		return err
@@ -71,10 +78,47 @@ func (p *Plugin) Init(ctx context.Context, cfgBytes []byte) error {
 
 	p.client = client
 	p.bucket = config.Bucket
+	p.configuration = config
+
+	p.tagging = encodeTags(config.Tags)
+
+	return nil
+}
+
+func validateConfig(config Config) error {
+	if config.Bucket == "" {
+		return fmt.Errorf("Bucket is mandatory")
+	}
+
+	if config.StorageClass != "" &&
+		!slices.Contains(types.StorageClass("").Values(), types.StorageClass(config.StorageClass)) {
+		return fmt.Errorf("Unknown storage class: %s", config.StorageClass)
+	}
+
+	sse := types.ServerSideEncryption(config.ServerSideEncryption.Type)
+	if sse != "" && !slices.Contains(sse.Values(), sse) {
+		return fmt.Errorf("Unknown server side encryption: %s", sse)
+	}
+	if config.ServerSideEncryption.KMSKeyID != "" &&
+		sse != types.ServerSideEncryptionAwsKms && sse != types.ServerSideEncryptionAwsKmsDsse {
+		return fmt.Errorf("KMSKeyID requires aws:kms server side encryption")
+	}
 
 	return nil
 }
 
+// encodeTags encodes the tags as the URL query expected by PutObject
+func encodeTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
 func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.ProduceResponse, error) {
 
 	bucket := p.bucket
@@ -87,12 +131,7 @@ func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.P
 		key = string(k)
 	}
 
-	// object will be stored with no content type
-	resp, err := p.client.PutObject(context.Background(), &s3.PutObjectInput{
-		Body:   bytes.NewReader(v),
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
+	resp, err := p.client.PutObject(context.Background(), p.putObjectInput(bucket, key, v, headers))
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +143,32 @@ func (p *Plugin) Produce(k []byte, v []byte, headers map[string]string) (*jrpc.P
 
 }
 
+func (p *Plugin) putObjectInput(bucket string, key string, v []byte, headers map[string]string) *s3.PutObjectInput {
+	input := &s3.PutObjectInput{
+		Body:                 bytes.NewReader(v),
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(key),
+		StorageClass:         types.StorageClass(p.configuration.StorageClass),
+		ServerSideEncryption: types.ServerSideEncryption(p.configuration.ServerSideEncryption.Type),
+		Metadata:             p.configuration.Metadata.Values(headers),
+	}
+
+	if p.configuration.ContentType != "" {
+		input.ContentType = aws.String(p.configuration.ContentType)
+	}
+	if p.configuration.ContentEncoding != "" {
+		input.ContentEncoding = aws.String(p.configuration.ContentEncoding)
+	}
+	if p.configuration.ServerSideEncryption.KMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(p.configuration.ServerSideEncryption.KMSKeyID)
+	}
+	if p.tagging != "" {
+		input.Tagging = aws.String(p.tagging)
+	}
+
+	return input
+}
+
 func (p *Plugin) Close(_ context.Context) error {
 	return nil
 }
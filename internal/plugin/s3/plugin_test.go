@@ -0,0 +1,161 @@
+//go:build s3
+// +build s3
+
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package s3_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/mapping"
+	"github.com/jrnd-io/jr-plugins/internal/plugin/s3"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  s3.Config
+		wantErr bool
+	}{
+		{
+			name:   "bucket only",
+			config: s3.Config{Bucket: "b"},
+		},
+		{
+			name: "storage class and kms",
+			config: s3.Config{
+				Bucket:               "b",
+				StorageClass:         "STANDARD_IA",
+				ServerSideEncryption: s3.ServerSideEncryption{Type: "aws:kms", KMSKeyID: "key"},
+			},
+		},
+		{
+			name:    "missing bucket",
+			config:  s3.Config{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown storage class",
+			config:  s3.Config{Bucket: "b", StorageClass: "COLD"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown server side encryption",
+			config:  s3.Config{Bucket: "b", ServerSideEncryption: s3.ServerSideEncryption{Type: "rot13"}},
+			wantErr: true,
+		},
+		{
+			name:    "kms key without kms encryption",
+			config:  s3.Config{Bucket: "b", ServerSideEncryption: s3.ServerSideEncryption{Type: "AES256", KMSKeyID: "key"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		err := s3.ValidateConfig(tt.config)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
+
+func TestPutObjectInput(t *testing.T) {
+	type input struct {
+		Bucket               string
+		Key                  string
+		ContentType          *string
+		ContentEncoding      *string
+		StorageClass         types.StorageClass
+		ServerSideEncryption types.ServerSideEncryption
+		SSEKMSKeyId          *string
+		Tagging              *string
+		Metadata             map[string]string
+	}
+
+	tests := []struct {
+		name    string
+		config  s3.Config
+		headers map[string]string
+		want    input
+	}{
+		{
+			name:   "defaults",
+			config: s3.Config{Bucket: "b"},
+			want:   input{Bucket: "b", Key: "k"},
+		},
+		{
+			name: "content type and kms",
+			config: s3.Config{
+				Bucket:               "b",
+				ContentType:          "application/json",
+				ContentEncoding:      "gzip",
+				StorageClass:         "GLACIER_IR",
+				ServerSideEncryption: s3.ServerSideEncryption{Type: "aws:kms", KMSKeyID: "arn:aws:kms:key"},
+			},
+			want: input{
+				Bucket:               "b",
+				Key:                  "k",
+				ContentType:          aws.String("application/json"),
+				ContentEncoding:      aws.String("gzip"),
+				StorageClass:         types.StorageClassGlacierIr,
+				ServerSideEncryption: types.ServerSideEncryptionAwsKms,
+				SSEKMSKeyId:          aws.String("arn:aws:kms:key"),
+			},
+		},
+		{
+			name: "tags and metadata",
+			config: s3.Config{
+				Bucket:   "b",
+				Tags:     map[string]string{"team": "data eng", "env": "a&b"},
+				Metadata: mapping.Mapping{Static: map[string]string{"source": "jr"}, FromHeaders: true},
+			},
+			headers: map[string]string{"trace": "t1"},
+			want: input{
+				Bucket:   "b",
+				Key:      "k",
+				Tagging:  aws.String("env=a%26b&team=data+eng"),
+				Metadata: map[string]string{"source": "jr", "trace": "t1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		got := s3.PutObjectInput(tt.config, "k", []byte("v"), tt.headers)
+		gotInput := input{
+			Bucket:               aws.ToString(got.Bucket),
+			Key:                  aws.ToString(got.Key),
+			ContentType:          got.ContentType,
+			ContentEncoding:      got.ContentEncoding,
+			StorageClass:         got.StorageClass,
+			ServerSideEncryption: got.ServerSideEncryption,
+			SSEKMSKeyId:          got.SSEKMSKeyId,
+			Tagging:              got.Tagging,
+			Metadata:             got.Metadata,
+		}
+		if diff := cmp.Diff(tt.want, gotInput); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package rolling holds the limits and naming shared by the plugins that
+// write records into rolling objects
+package rolling
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Limits define when the current object is closed and a new one is started
+type Limits struct {
+	MaxBytes   int64
+	MaxRecords int
+	MaxAge     time.Duration
+}
+
+// ParseLimits parses the configured limits, at least one of them is mandatory
+func ParseLimits(maxBytes int64, maxRecords int, maxAge string) (Limits, error) {
+	limits := Limits{
+		MaxBytes:   maxBytes,
+		MaxRecords: maxRecords,
+	}
+
+	if maxAge != "" {
+		d, err := time.ParseDuration(maxAge)
+		if err != nil {
+			return Limits{}, err
+		}
+		limits.MaxAge = d
+	}
+
+	if limits.MaxBytes <= 0 && limits.MaxRecords <= 0 && limits.MaxAge <= 0 {
+		return Limits{}, fmt.Errorf("At least one of MaxBytes, MaxRecords or MaxAge is mandatory")
+	}
+	return limits, nil
+}
+
+// Reached reports whether an object holding size bytes and records records
+// must be closed, the age limit is enforced by the writers with a timer
+func (l Limits) Reached(size int64, records int) bool {
+	return (l.MaxRecords > 0 && records >= l.MaxRecords) ||
+		(l.MaxBytes > 0 && size >= l.MaxBytes)
+}
+
+// Name returns the name of the seq-th object, e.g. jr/20240102T030405Z-000001.ndjson
+func Name(prefix string, t time.Time, seq int, suffix string) string {
+	return fmt.Sprintf("%s%s-%06d%s", prefix, t.UTC().Format("20060102T150405Z"), seq, suffix)
+}
+
+// JSONLine compacts the JSON value into a single line terminated by a newline,
+// jr templates may produce pretty printed values that break line based readers
+func JSONLine(v []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, v); err != nil {
+		return nil, fmt.Errorf("Record is not valid JSON: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rolling_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jrnd-io/jr-plugins/internal/rolling"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxBytes   int64
+		maxRecords int
+		maxAge     string
+		want       rolling.Limits
+		wantErr    bool
+	}{
+		{
+			name:       "all",
+			maxBytes:   1024,
+			maxRecords: 10,
+			maxAge:     "5m",
+			want:       rolling.Limits{MaxBytes: 1024, MaxRecords: 10, MaxAge: 5 * time.Minute},
+		},
+		{
+			name:   "age only",
+			maxAge: "1s",
+			want:   rolling.Limits{MaxAge: time.Second},
+		},
+		{
+			name:    "none",
+			wantErr: true,
+		},
+		{
+			name:    "invalid age",
+			maxAge:  "five minutes",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := rolling.ParseLimits(tt.maxBytes, tt.maxRecords, tt.maxAge)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestReached(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  rolling.Limits
+		size    int64
+		records int
+		want    bool
+	}{
+		{name: "below", limits: rolling.Limits{MaxBytes: 100, MaxRecords: 10}, size: 99, records: 9, want: false},
+		{name: "bytes", limits: rolling.Limits{MaxBytes: 100, MaxRecords: 10}, size: 100, records: 1, want: true},
+		{name: "records", limits: rolling.Limits{MaxBytes: 100, MaxRecords: 10}, size: 1, records: 10, want: true},
+		{name: "age only", limits: rolling.Limits{MaxAge: time.Second}, size: 1 << 30, records: 1 << 20, want: false},
+	}
+
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, tt.limits.Reached(tt.size, tt.records)); diff != "" {
+			t.Errorf("%s: mismatch (-want +got):\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600))
+	got := rolling.Name("jr/", ts, 7, ".ndjson.gz")
+	if diff := cmp.Diff("jr/20240102T020405Z-000007.ndjson.gz", got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestJSONLine(t *testing.T) {
+	got, err := rolling.JSONLine([]byte("{\n  \"id\": 1,\n  \"name\": \"jr\"\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("{\"id\":1,\"name\":\"jr\"}\n", string(got)); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := rolling.JSONLine([]byte("not json")); err == nil {
+		t.Errorf("expected an error for an invalid JSON value")
+	}
+}